@@ -35,6 +35,46 @@ describe('Built-in Functions', () => {
     });
   });
 
+  describe('get-in and set-in functions', () => {
+    const app = `set app {"users": [{"name": "Ana", "address": {"city": "Porto"}}, {"name": "Bo"}]}`;
+
+    test('reads nested fields and returns null for missing steps', () => {
+      interpreter.evaluate(parse(app));
+      expect(interpreter.evaluate(parse('get-in app ["users", 0, "address", "city"]'))).toBe('Porto');
+      expect(interpreter.evaluate(parse('get-in app ["users", 1, "address", "city"]'))).toBe(null);
+      expect(interpreter.evaluate(parse('get-in app ["users", 5, "name"]'))).toBe(null);
+    });
+
+    test('ignores inherited members', () => {
+      expect(interpreter.evaluate(parse('get-in {} ["constructor"]'))).toBe(null);
+      expect(interpreter.evaluate(parse('get-in {"a": {}} ["a", "toString"]'))).toBe(null);
+      expect(interpreter.evaluate(parse('get-in [1, 2] ["length"]'))).toBe(null);
+    });
+
+    test('updates nested fields and shares untouched branches', () => {
+      interpreter.evaluate(parse(`${app}
+set updated (set-in app ["users", 0, "address", "city"] "Lisboa")`));
+      const before = interpreter.evaluate(parse('app'));
+      const after = interpreter.evaluate(parse('updated'));
+      
+      expect(after.users[0].address.city).toBe('Lisboa');
+      expect(before.users[0].address.city).toBe('Porto');
+      expect(after.users[1]).toBe(before.users[1]);
+    });
+
+    test('creates missing objects along the path', () => {
+      const program = parse('set-in {} ["settings", "theme"] "dark"');
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual({ settings: { theme: 'dark' } });
+    });
+
+    test('throws error for out-of-range list indexes', () => {
+      expect(() => {
+        interpreter.evaluate(parse('set-in [1, 2] [5] 0'));
+      }).toThrow('Cannot set index 5 of a list with 2 items');
+    });
+  });
+
   describe('for function', () => {
     test('creates components from string list', () => {
       const program = parse(`set names ["Alice", "Bob"]
@@ -118,6 +158,351 @@ for "not an array" card_maker`);
     });
   });
 
+  describe('equals, approx-equals and compare functions', () => {
+    test('equals compares lists and objects by content', () => {
+      expect(interpreter.evaluate(parse('equals [1, {"a": 2, "b": 3}] [1, {"b": 3, "a": 2}]'))).toBe(true);
+      expect(interpreter.evaluate(parse('equals {"a": [1, 2]} {"a": [2, 1]}'))).toBe(false);
+      expect(interpreter.evaluate(parse('equals "x" "x"'))).toBe(true);
+      expect(interpreter.evaluate(parse('equal [1] [1]'))).toBe(false);
+    });
+
+    test('equals matches functions by identity inside lists and objects', () => {
+      interpreter.evaluate(parse('set f {x: x}'));
+      interpreter.evaluate(parse('set g {x: x}'));
+
+      expect(interpreter.evaluate(parse('equals (list f) (list f)'))).toBe(true);
+      expect(interpreter.evaluate(parse('equals (set-in {} ["on"] f) (set-in {} ["on"] f)'))).toBe(true);
+      expect(interpreter.evaluate(parse('equals (list f) (list g)'))).toBe(false);
+    });
+
+    test('approx-equals tolerates float rounding', () => {
+      expect(interpreter.evaluate(parse('equal (+ 0.1 0.2) 0.3'))).toBe(false);
+      expect(interpreter.evaluate(parse('approx-equals (+ 0.1 0.2) 0.3'))).toBe(true);
+      expect(interpreter.evaluate(parse('approx-equals 1.0 1.05 0.1'))).toBe(true);
+      expect(interpreter.evaluate(parse('approx-equals 1.0 1.5 0.1'))).toBe(false);
+      expect(() => interpreter.evaluate(parse('approx-equals "1" 1'))).toThrow('approx-equals expects numbers to compare');
+    });
+
+    test('compare returns -1, 0 or 1', () => {
+      expect(interpreter.evaluate(parse('compare 1 2'))).toBe(-1);
+      expect(interpreter.evaluate(parse('compare "b" "a"'))).toBe(1);
+      expect(interpreter.evaluate(parse('compare [1, 2] [1, 2]'))).toBe(0);
+      expect(interpreter.evaluate(parse('compare null 0'))).toBe(-1);
+    });
+
+    test('compare orders objects by field names, then values', () => {
+      expect(interpreter.evaluate(parse('compare {"a": 1} {"a": 2}'))).toBe(-1);
+      expect(interpreter.evaluate(parse('compare {"b": 1, "a": 2} {"a": 2, "b": 1}'))).toBe(0);
+      expect(interpreter.evaluate(parse('compare {"b": 0} {"a": 9}'))).toBe(1);
+    });
+
+    test('compare places NaN after other numbers and equal to itself', () => {
+      interpreter.evaluate(parse('set n (% 5 0)'));
+
+      expect(interpreter.evaluate(parse('compare n n'))).toBe(0);
+      expect(interpreter.evaluate(parse('equals n n'))).toBe(true);
+      expect(interpreter.evaluate(parse('compare n 1e308'))).toBe(1);
+      expect(interpreter.evaluate(parse('compare 0 n'))).toBe(-1);
+      expect(interpreter.evaluate(parse('sort-by (list 1 n 0) {v: v}'))).toEqual([0, 1, NaN]);
+    });
+
+    test('compare rejects functions', () => {
+      interpreter.evaluate(parse('set f {x: x}'));
+      interpreter.evaluate(parse('set g {x: x}'));
+      expect(() => interpreter.evaluate(parse('compare f g'))).toThrow('Cannot order functions');
+    });
+  });
+
+  describe('round and format-decimal functions', () => {
+    test('rounds halves away from zero without float artifacts', () => {
+      expect(interpreter.evaluate(parse('round 1.005 2'))).toBe(1.01);
+      expect(interpreter.evaluate(parse('round -2.5'))).toBe(-3);
+      expect(interpreter.evaluate(parse('round 2.675 2'))).toBe(2.68);
+    });
+
+    test('rounds numbers too large to shift by the decimal places', () => {
+      expect(interpreter.evaluate(parse('round 1e17 4'))).toBe(1e17);
+      expect(interpreter.evaluate(parse('round 123456789012345678 4'))).toBe(123456789012345678);
+      expect(interpreter.evaluate(parse('round -1e17 4'))).toBe(-1e17);
+      expect(interpreter.evaluate(parse('format-decimal 1e17 2'))).toBe('100000000000000000.00');
+    });
+
+    test('formats with a fixed number of decimal places', () => {
+      expect(interpreter.evaluate(parse('format-decimal 19.9 2'))).toBe('19.90');
+      expect(interpreter.evaluate(parse('format-decimal (* 0.1 3) 2'))).toBe('0.30');
+      expect(interpreter.evaluate(parse('format-decimal 1.005 2'))).toBe('1.01');
+    });
+
+    test('throws error for invalid decimal places', () => {
+      expect(() => {
+        interpreter.evaluate(parse('format-decimal 1 -1'));
+      }).toThrow('format-decimal expects decimal places to be an integer between 0 and 20');
+    });
+  });
+
+  describe('length, chars and bytes functions', () => {
+    test('counts Unicode code points rather than UTF-16 units', () => {
+      expect(interpreter.evaluate(parse('length "café 🌱"'))).toBe(6);
+      expect(interpreter.evaluate(parse('length [1, 2, 3]'))).toBe(3);
+    });
+
+    test('splits strings into code points', () => {
+      const program = parse('chars "a🌱é"');
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual(['a', '🌱', 'é']);
+    });
+
+    test('encodes strings as UTF-8 bytes', () => {
+      const program = parse('bytes "aé€🌱"');
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual([97, 195, 169, 226, 130, 172, 240, 159, 140, 177]);
+    });
+
+    test('throws error for unsupported values', () => {
+      expect(() => {
+        interpreter.evaluate(parse('length 42'));
+      }).toThrow('length expects a string or list');
+    });
+  });
+
+  describe('sort-by function', () => {
+    test('sorts by key and keeps the order of equal keys', () => {
+      const program = parse(`set people [{"name": "Ana", "age": 30}, {"name": "Bo", "age": 25}, {"name": "Cy", "age": 30}]
+sort-by people {p: get p "age"}`);
+      const result = interpreter.evaluate(program);
+      expect(result.map((p: any) => p.name)).toEqual(['Bo', 'Ana', 'Cy']);
+    });
+
+    test('sorts strings by code point without a locale', () => {
+      const program = parse('sort-by ["zebra", "Émile", "eve"] {s: s}');
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual(['eve', 'zebra', 'Émile']);
+    });
+
+    test('sorts characters outside the BMP after U+E000-U+FFFF', () => {
+      const program = parse('sort-by ["🌱", "！", "a"] {s: s}');
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual(['a', '！', '🌱']);
+    });
+
+    test('sorts strings with a collator when given a locale', () => {
+      const program = parse('sort-by ["zebra", "Émile", "eve"] {s: s} "en"');
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual(['Émile', 'eve', 'zebra']);
+    });
+
+    test('orders mixed key types consistently', () => {
+      const program = parse('sort-by ["b", 2, null, true, 1, "a"] {v: v}');
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual([null, true, 1, 2, 'a', 'b']);
+    });
+
+    test('throws error for an invalid locale', () => {
+      expect(() => {
+        interpreter.evaluate(parse('sort-by ["a"] {s: s} "not a locale!"'));
+      }).toThrow('sort-by expects third argument to be a valid locale, got: "not a locale!"');
+    });
+
+    test('throws error for non-function key', () => {
+      expect(() => {
+        interpreter.evaluate(parse('sort-by [1, 2] "key"'));
+      }).toThrow('sort-by expects second argument to be a function');
+    });
+  });
+
+  describe('select function', () => {
+    const user = `set user {"id": 7, "name": "Ana", "email": "ana@example.org", "password": "x", "address": {"city": "Porto", "street": "Rua 1"}}`;
+
+    test('keeps only the selected fields in order of selection', () => {
+      const program = parse(`${user}
+select user ["name", "id"]`);
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual({ name: 'Ana', id: 7 });
+      expect(Object.keys(result)).toEqual(['name', 'id']);
+    });
+
+    test('supports nested selections', () => {
+      const program = parse(`${user}
+select user ["id", {"address": ["city"]}]`);
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual({ id: 7, address: { city: 'Porto' } });
+    });
+
+    test('projects every object in a list and skips missing fields', () => {
+      const program = parse('select [{"id": 1, "x": 1}, {"x": 2}] ["id"]');
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual([{ id: 1 }, {}]);
+    });
+
+    test('ignores inherited members', () => {
+      const program = parse('select {"id": 1} ["id", "toString", {"constructor": ["name"]}]');
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual({ id: 1 });
+      expect(Object.keys(result)).toEqual(['id']);
+    });
+
+    test('throws error for invalid field lists', () => {
+      expect(() => {
+        interpreter.evaluate(parse('select {"id": 1} "id"'));
+      }).toThrow('select expects second argument to be a list of field names');
+    });
+  });
+
+  describe('remove-where function', () => {
+    test('removes items matching the predicate', () => {
+      const program = parse('remove-where [1, 2, 3, 4] {n: equal (% n 2) 0}');
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual([1, 3]);
+    });
+
+    test('leaves the original list untouched', () => {
+      const program = parse(`set posts [{"id": 1, "draft": true}, {"id": 2, "draft": false}]
+set published (remove-where posts {p: get p "draft"})
+posts`);
+      const result = interpreter.evaluate(program);
+      expect(result).toHaveLength(2);
+      expect(interpreter.evaluate(parse('published'))).toEqual([{ id: 2, draft: false }]);
+    });
+
+    test('throws error for non-function', () => {
+      expect(() => {
+        const program = parse('remove-where [1, 2] "not a function"');
+        interpreter.evaluate(program);
+      }).toThrow('remove-where expects second argument to be a function');
+    });
+  });
+
+  describe('index-by function', () => {
+    test('indexes objects by key for direct lookup', () => {
+      const program = parse(`set posts [{"id": 1, "title": "Hello"}, {"id": 2, "title": "Relay"}]
+set by_id (index-by posts "id")
+get by_id 2`);
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual({ id: 2, title: 'Relay' });
+    });
+
+    test('skips items without the key', () => {
+      const program = parse('index-by [{"id": "a"}, {"name": "no id"}] "id"');
+      const result = interpreter.evaluate(program);
+      expect(Object.keys(result)).toEqual(['a']);
+    });
+
+    test('indexes keys that name Object.prototype members', () => {
+      interpreter.evaluate(parse('set by_id (index-by [{"id": "__proto__"}, {"id": "constructor"}] "id")'));
+      expect(Object.keys(interpreter.evaluate(parse('by_id')))).toEqual(['__proto__', 'constructor']);
+      expect(interpreter.evaluate(parse('get by_id "__proto__"'))).toEqual({ id: '__proto__' });
+    });
+
+    test('throws error for non-array', () => {
+      expect(() => {
+        const program = parse('index-by "posts" "id"');
+        interpreter.evaluate(program);
+      }).toThrow('index-by expects first argument to be a list/array');
+    });
+  });
+
+  describe('search function', () => {
+    const posts = `set posts [
+  {"title": "Relay federation", "body": "How relay nodes talk"},
+  {"title": "Cooking", "body": "Bread and relay races"},
+  {"title": "Federation notes", "body": "Relay relay relay"}
+]`;
+
+    test('returns items matching every query term, best match first', () => {
+      const program = parse(`${posts}
+search posts "federation relay" ["title", "body"]`);
+      const result = interpreter.evaluate(program);
+      expect(result.map((post: any) => post.title)).toEqual(['Federation notes', 'Relay federation']);
+    });
+
+    test('only searches the given fields', () => {
+      const program = parse(`${posts}
+search posts "bread" ["title"]`);
+      const result = interpreter.evaluate(program);
+      expect(result).toEqual([]);
+    });
+
+    test('searches all string fields when no fields are given', () => {
+      const program = parse(`${posts}
+search posts "BREAD"`);
+      const result = interpreter.evaluate(program);
+      expect(result).toHaveLength(1);
+      expect(result[0].title).toBe('Cooking');
+    });
+
+    test('throws error for non-string query', () => {
+      expect(() => {
+        const program = parse('search [] 42');
+        interpreter.evaluate(program);
+      }).toThrow('search expects second argument to be a query string');
+    });
+  });
+
+  describe('message catalog functions', () => {
+    const catalogs = `def-messages "en" {"greeting": "Hello {name}", "bye": "Goodbye"}
+def-messages "es" {"greeting": "Hola {name}"}`;
+
+    test('translates with the default locale', () => {
+      const program = parse(`${catalogs}
+translate "greeting" {"name": "Ana"}`);
+      const result = interpreter.evaluate(program);
+      expect(result).toBe('Hello Ana');
+    });
+
+    test('uses the active locale and falls back by language then English', () => {
+      interpreter.evaluate(parse(`${catalogs}
+set-locale "es-MX"`));
+      expect(interpreter.evaluate(parse('translate "greeting" {"name": "Ana"}'))).toBe('Hola Ana');
+      expect(interpreter.evaluate(parse('translate "bye"'))).toBe('Goodbye');
+    });
+
+    test('leaves placeholders without a matching param untouched', () => {
+      interpreter.evaluate(parse('def-messages "en" {"built": "Built by {constructor} for {name}"}'));
+      expect(interpreter.evaluate(parse('translate "built" {"name": "Ana"}'))).toBe('Built by {constructor} for Ana');
+    });
+
+    test('returns the key for unknown messages', () => {
+      const program = parse('translate "missing.key"');
+      const result = interpreter.evaluate(program);
+      expect(result).toBe('missing.key');
+    });
+  });
+
+  describe('redact function', () => {
+    let log: jest.SpyInstance;
+
+    beforeEach(() => {
+      log = jest.spyOn(console, 'log').mockImplementation(() => {});
+    });
+
+    afterEach(() => {
+      log.mockRestore();
+    });
+
+    const logged = () => JSON.stringify(log.mock.calls);
+
+    test('masks redacted object fields in state logs', () => {
+      interpreter.evaluate(parse(`redact "password"
+state user {"name": "Ana", "password": "hunter2"}`));
+      expect(logged()).toContain('[REDACTED]');
+      expect(logged()).not.toContain('hunter2');
+      expect(logged()).toContain('Ana');
+    });
+
+    test('masks redacted variables entirely', () => {
+      interpreter.evaluate(parse(`redact "token"
+set token "abc123"`));
+      expect(logged()).not.toContain('abc123');
+    });
+
+    test('does not change the values themselves', () => {
+      const result = interpreter.evaluate(parse(`redact "password"
+set user {"password": "hunter2"}
+get user "password"`));
+      expect(result).toBe('hunter2');
+    });
+  });
+
   describe('integration test', () => {
     test('for and get work together in show block syntax', () => {
       const program = parse(`set products [{"name": "Laptop", "price": 999}, {"name": "Phone", "price": 599}]
@@ -0,0 +1,121 @@
+import { tokenize, parse, MAX_NESTING_DEPTH } from '../parser';
+import { RelayInterpreter } from '../interpreter';
+import { createRandom } from '../testing';
+
+// Fragments that exercise every token type, including malformed ones
+const FRAGMENTS = [
+  'show', 'set', 'def', 'if', 'for', 'get', 'concat', 'list', 'add', 'state', 'x', 'item',
+  '"text"', '"esc\\"aped"', '"', '\\', '1', '-2', '3.5', '0', '.',
+  '(', ')', '[', ']', '{', '}', ':', ',', '{x: x}',
+  '+', '-', '*', '/', '%', '<', '>', '=', '!',
+  'true', 'false', 'null', '#comment', '\n', '\n  ', '\n    ', '\t', ' ', 'é', '🌱'
+];
+
+function randomProgram(random: () => number): string {
+  const length = 1 + Math.floor(random() * 16);
+  let source = '';
+  for (let i = 0; i < length; i++) {
+    source += FRAGMENTS[Math.floor(random() * FRAGMENTS.length)];
+    if (random() < 0.5) source += ' ';
+  }
+  return source;
+}
+
+// Anything other than a plain Error means the input reached an unhardened path
+function expectCleanFailure(run: () => void, source: string): void {
+  try {
+    run();
+  } catch (error) {
+    if (!(error instanceof Error) || error.constructor !== Error) {
+      throw new Error(`Unexpected ${(error as any)?.constructor?.name} for input ${JSON.stringify(source)}: ${(error as any)?.message}`);
+    }
+  }
+}
+
+describe('Fuzzing', () => {
+  const ITERATIONS = 2000;
+
+  beforeEach(() => {
+    jest.spyOn(console, 'log').mockImplementation(() => {});
+  });
+
+  afterEach(() => {
+    jest.restoreAllMocks();
+  });
+
+  test('lexer only fails with descriptive errors', () => {
+    const random = createRandom(4952);
+    for (let i = 0; i < ITERATIONS; i++) {
+      const source = randomProgram(random);
+      expectCleanFailure(() => tokenize(source), source);
+    }
+  });
+
+  test('parser only fails with descriptive errors', () => {
+    const random = createRandom(1337);
+    for (let i = 0; i < ITERATIONS; i++) {
+      const source = randomProgram(random);
+      expectCleanFailure(() => parse(source), source);
+    }
+  });
+
+  test('evaluator only fails with descriptive errors', () => {
+    const random = createRandom(2024);
+    for (let i = 0; i < ITERATIONS; i++) {
+      const source = randomProgram(random);
+      expectCleanFailure(() => new RelayInterpreter().evaluate(parse(source)), source);
+    }
+  });
+
+  test('deeply nested input is rejected instead of overflowing the stack', () => {
+    const depth = MAX_NESTING_DEPTH * 10;
+    const inputs = [
+      '('.repeat(depth) + 'x' + ')'.repeat(depth),
+      '['.repeat(depth) + ']'.repeat(depth),
+      '{"a": '.repeat(depth) + '1' + '}'.repeat(depth),
+      Array.from({ length: depth }, (_, i) => ' '.repeat(i) + 'show x').join('\n')
+    ];
+
+    for (const source of inputs) {
+      expect(() => parse(source)).toThrow('Expression nesting exceeds');
+    }
+  });
+
+  test('nesting up to the limit still parses and evaluates', () => {
+    const depth = MAX_NESTING_DEPTH - 1;
+    const source = '['.repeat(depth) + '1' + ']'.repeat(depth);
+    let result = new RelayInterpreter().evaluate(parse(source));
+    for (let i = 0; i < depth; i++) {
+      expect(result).toHaveLength(1);
+      result = result[0];
+    }
+    expect(result).toBe(1);
+  });
+
+  test('JSON values survive a parse and evaluate round trip', () => {
+    const random = createRandom(7);
+    const randomValue = (level: number): any => {
+      const kind = Math.floor(random() * (level > 3 ? 4 : 6));
+      switch (kind) {
+        case 0: return Math.floor(random() * 2000) - 1000;
+        case 1: return `s${Math.floor(random() * 100)} "quoted" \\ tab\t`;
+        case 2: return random() < 0.5;
+        case 3: return null;
+        case 4: return Array.from({ length: Math.floor(random() * 4) }, () => randomValue(level + 1));
+        default: {
+          const object: Record<string, any> = {};
+          for (let i = 0; i < Math.floor(random() * 4); i++) {
+            object[`key${i}`] = randomValue(level + 1);
+          }
+          return object;
+        }
+      }
+    };
+
+    for (let i = 0; i < 200; i++) {
+      const value = { value: randomValue(0) };
+      const source = JSON.stringify(value);
+      expect(new RelayInterpreter().evaluate(parse(source))).toEqual(value);
+    }
+  });
+});
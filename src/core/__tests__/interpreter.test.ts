@@ -47,6 +47,11 @@ describe('RelayInterpreter', () => {
       expect(result).toBe(1);
     });
 
+    test('evaluates numeric literal forms in any argument position', () => {
+      expect(interpreter.evaluate(parse('+ 1_000 0x10 1e2 -2.5e1'))).toBe(1091);
+      expect(interpreter.evaluate(parse('[1_0, -0xA]'))).toEqual([10, -10]);
+    });
+
     test('throws error on division by zero', () => {
       const program = parse('/ 5 0');
       expect(() => interpreter.evaluate(program)).toThrow('Division by zero');
@@ -99,6 +104,89 @@ describe('RelayInterpreter', () => {
       expect(interpreter.evaluate(program1)).toBe(true);
       expect(interpreter.evaluate(program2)).toBe(false);
     });
+
+    test('evaluates chained comparisons', () => {
+      expect(interpreter.evaluate(parse('< 1 5 10'))).toBe(true);
+      expect(interpreter.evaluate(parse('< 1 10 5'))).toBe(false);
+      expect(interpreter.evaluate(parse('> 10 5 5'))).toBe(false);
+      expect(interpreter.evaluate(parse('< "a" "b" "c"'))).toBe(true);
+    });
+
+    test('evaluates infix chains as a single comparison', () => {
+      interpreter.evaluate(parse('set x 5'));
+
+      expect(interpreter.evaluate(parse('0 < x < 10'))).toBe(true);
+      expect(interpreter.evaluate(parse('x < 3 < 10'))).toBe(false);
+      expect(interpreter.evaluate(parse('if (10 > x > 0) "in" "out"'))).toBe('in');
+    });
+
+    test('does not treat other number-led operators as infix', () => {
+      // Infix groups from the right, so accepting this would silently compute 10 - (3 - 2)
+      expect(() => interpreter.evaluate(parse('10 - 3 - 2'))).toThrow();
+      expect(() => interpreter.evaluate(parse('2 * 3 + 1'))).toThrow();
+    });
+
+    test('compares mixed types loosely outside strict mode', () => {
+      interpreter.evaluate(parse('set count null'));
+      expect(interpreter.evaluate(parse('> count 0'))).toBe(false);
+      expect(() => interpreter.evaluate(parse('< 1'))).toThrow('< expects at least 2 arguments');
+    });
+
+    test('rejects comparisons between different types in strict mode', () => {
+      const strict = new RelayInterpreter({ strict: true });
+      expect(() => strict.evaluate(parse('< 1 "2"'))).toThrow('< expects all numbers or all strings');
+      expect(() => strict.evaluate(parse('> true 0'))).toThrow('> expects all numbers or all strings');
+      expect(() => strict.evaluate(parse('between null 0 10'))).toThrow('between expects all numbers or all strings');
+    });
+
+    test('evaluates between inclusively', () => {
+      expect(interpreter.evaluate(parse('between 5 1 10'))).toBe(true);
+      expect(interpreter.evaluate(parse('between 1 1 10'))).toBe(true);
+      expect(interpreter.evaluate(parse('between 10 1 10'))).toBe(true);
+      expect(interpreter.evaluate(parse('between 11 1 10'))).toBe(false);
+      expect(interpreter.evaluate(parse('between "m" "a" "z"'))).toBe(true);
+      expect(() => interpreter.evaluate(parse('between 5 1'))).toThrow('between expects exactly 3 arguments');
+    });
+  });
+
+  describe('Constants', () => {
+    test('defines constants that can be read like variables', () => {
+      interpreter.evaluate(parse('const MAX_POSTS 100'));
+      expect(interpreter.evaluate(parse('MAX_POSTS'))).toBe(100);
+      expect(interpreter.evaluate(parse('* MAX_POSTS 2'))).toBe(200);
+    });
+
+    test('rejects reassigning a constant', () => {
+      interpreter.evaluate(parse('const MAX_POSTS 100'));
+
+      expect(() => interpreter.evaluate(parse('set MAX_POSTS 5'))).toThrow('Cannot reassign constant MAX_POSTS');
+      expect(() => interpreter.evaluate(parse('const MAX_POSTS 5'))).toThrow('Cannot reassign constant MAX_POSTS');
+      expect(() => interpreter.evaluate(parse('def MAX_POSTS x x'))).toThrow('Cannot reassign constant MAX_POSTS');
+      expect(interpreter.evaluate(parse('MAX_POSTS'))).toBe(100);
+    });
+
+    test('rejects reassigning a constant inside a function', () => {
+      interpreter.evaluate(parse('const LIMIT 10'));
+      interpreter.evaluate(parse(`def bump x
+    set LIMIT x`));
+
+      expect(() => interpreter.evaluate(parse('bump 20'))).toThrow('Cannot reassign constant LIMIT');
+    });
+
+    test('lets a parameter shadow a constant', () => {
+      interpreter.evaluate(parse('const n 5'));
+      interpreter.evaluate(parse(`def f n
+    set n 1
+    n`));
+
+      expect(interpreter.evaluate(parse('f 2'))).toBe(1);
+      expect(interpreter.evaluate(parse('n'))).toBe(5);
+    });
+
+    test('rejects turning an existing variable into a constant', () => {
+      interpreter.evaluate(parse('set limit 10'));
+      expect(() => interpreter.evaluate(parse('const limit 20'))).toThrow('Cannot declare constant limit: it is already defined');
+    });
   });
 
   describe('Conditional Operations', () => {
@@ -125,6 +213,87 @@ describe('RelayInterpreter', () => {
       const result = interpreter.evaluate(program);
       expect(result).toBe('no');
     });
+
+    test('evaluates the single-line if then else form', () => {
+      interpreter.evaluate(parse('set score 7'));
+
+      expect(interpreter.evaluate(parse('if (> score 5) then "pass" else "fail"'))).toBe('pass');
+      expect(interpreter.evaluate(parse('if (> score 9) then "pass" else "fail"'))).toBe('fail');
+      expect(interpreter.evaluate(parse('{"grade": if (> score 5) then "pass" else "fail", "score": score}')))
+        .toEqual({ grade: 'pass', score: 7 });
+      expect(interpreter.evaluate(parse('concat "result: " (if (> score 5) then "pass" else "fail")'))).toBe('result: pass');
+      expect(interpreter.evaluate(parse('if score > 5 then "pass" else "fail"'))).toBe('pass');
+      expect(interpreter.evaluate(parse('if score < 5 then "pass" else "fail"'))).toBe('fail');
+    });
+  });
+
+  describe('Logical Operations', () => {
+    test('and returns the first falsy operand or the last one', () => {
+      expect(interpreter.evaluate(parse('and 1 "two" 3'))).toBe(3);
+      expect(interpreter.evaluate(parse('and 1 null 3'))).toBe(null);
+      expect(interpreter.evaluate(parse('and true 0'))).toBe(0);
+    });
+
+    test('or returns the first truthy operand or the last one', () => {
+      interpreter.evaluate(parse('set name null'));
+
+      expect(interpreter.evaluate(parse('or name "anonymous"'))).toBe('anonymous');
+      expect(interpreter.evaluate(parse('or "alice" "anonymous"'))).toBe('alice');
+      expect(interpreter.evaluate(parse('or false 0'))).toBe(0);
+    });
+
+    test('stops evaluating once the result is known', () => {
+      interpreter.evaluate(parse('set count 0'));
+
+      interpreter.evaluate(parse('or true (set count 1)'));
+      interpreter.evaluate(parse('and false (set count 2)'));
+      expect(interpreter.evaluate(parse('count'))).toBe(0);
+
+      interpreter.evaluate(parse('and true (set count 3)'));
+      expect(interpreter.evaluate(parse('count'))).toBe(3);
+    });
+
+    test('requires at least one operand', () => {
+      expect(() => interpreter.evaluate(parse('and'))).toThrow('and expects at least 1 argument');
+      expect(() => interpreter.evaluate(parse('or'))).toThrow('or expects at least 1 argument');
+    });
+  });
+
+  describe('Type Operations', () => {
+    test('type-of names the type of a value', () => {
+      expect(interpreter.evaluate(parse('type-of 1.5'))).toBe('number');
+      expect(interpreter.evaluate(parse('type-of "text"'))).toBe('string');
+      expect(interpreter.evaluate(parse('type-of false'))).toBe('boolean');
+      expect(interpreter.evaluate(parse('type-of null'))).toBe('null');
+      expect(interpreter.evaluate(parse('type-of [1, 2]'))).toBe('list');
+      expect(interpreter.evaluate(parse('type-of {"a": 1}'))).toBe('object');
+      expect(interpreter.evaluate(parse('type-of {x: x}'))).toBe('function');
+    });
+
+    test('match-type calls the case for the value type', () => {
+      interpreter.evaluate(parse(`def describe value
+    match-type value {
+        "number": {n: * n 2},
+        "string": {s: concat s "!"},
+        "_": "other"
+    }`));
+
+      expect(interpreter.evaluate(parse('describe 21'))).toBe(42);
+      expect(interpreter.evaluate(parse('describe "hi"'))).toBe('hi!');
+      expect(interpreter.evaluate(parse('describe [1]'))).toBe('other');
+    });
+
+    test('match-type only calls the matching case', () => {
+      const program = parse('match-type "a" {"number": {n: missing-function n}, "string": {s: concat s s}}');
+      expect(interpreter.evaluate(program)).toBe('aa');
+    });
+
+    test('match-type reports values without a matching case', () => {
+      expect(() => interpreter.evaluate(parse('match-type true {"number": 1, "string": 2}')))
+        .toThrow('match-type has no case for type boolean');
+      expect(() => interpreter.evaluate(parse('match-type 1 [1]')))
+        .toThrow('match-type expects second argument to be an object of cases');
+    });
   });
 
   describe('JSON Data Structures', () => {
@@ -140,6 +309,20 @@ describe('RelayInterpreter', () => {
       expect(result).toEqual({ name: 'test', value: 42 });
     });
 
+    test('preserves object field order through evaluation and JSON encoding', () => {
+      const program = parse('{"zeta": 1, "alpha": {"y": true, "b": null}, "mid": [3, 2, 1]}');
+      const result = interpreter.evaluate(program);
+      expect(Object.keys(result)).toEqual(['zeta', 'alpha', 'mid']);
+      expect(Object.keys(result.alpha)).toEqual(['y', 'b']);
+      expect(JSON.stringify(result)).toBe('{"zeta":1,"alpha":{"y":true,"b":null},"mid":[3,2,1]}');
+    });
+
+    test('preserves field order in show props', () => {
+      const program = parse('show "card" {"title": "T", "content": "C", "class": "x"}');
+      const result = interpreter.evaluate(program);
+      expect(Object.keys(result.components[0].props)).toEqual(['title', 'content', 'class']);
+    });
+
     test('evaluates arrays with expressions', () => {
       const program = parse('[+ 1 2, * 3 4]');
       const result = interpreter.evaluate(program);
@@ -355,4 +538,138 @@ describe('Custom Builtins', () => {
     const result3 = testInterpreter.evaluate(parse('triple 7'));
     expect(result3).toBe(21);
   });
-}); 
\ No newline at end of file
+});
+
+describe('Ensure blocks', () => {
+  let interpreter: RelayInterpreter;
+  let log: any[];
+
+  beforeEach(() => {
+    interpreter = new RelayInterpreter();
+    log = [];
+    defineBuiltin('record', true, (args: any[]) => {
+      log.push(args[0]);
+      return args[0];
+    });
+  });
+
+  afterEach(() => {
+    delete builtins['record'];
+  });
+
+  test('runs cleanup after the function body, keeping its result', () => {
+    interpreter.evaluate(parse(`def work x
+    ensure
+        record "closed"
+    record "working"
+    * x 2`));
+
+    expect(interpreter.evaluate(parse('work 21'))).toBe(42);
+    expect(log).toEqual(['working', 'closed']);
+  });
+
+  test('runs cleanup in reverse order', () => {
+    interpreter.evaluate(parse(`def work x
+    ensure (record "first")
+    ensure (record "second")
+    x`));
+
+    interpreter.evaluate(parse('work 1'));
+    expect(log).toEqual(['second', 'first']);
+  });
+
+  test('runs cleanup when the body fails and keeps the original error', () => {
+    interpreter.evaluate(parse(`def work x
+    ensure (record "closed")
+    / x "zero"`));
+
+    expect(() => interpreter.evaluate(parse('work 1'))).toThrow();
+    expect(log).toEqual(['closed']);
+  });
+
+  test('only runs cleanup registered before the failure', () => {
+    interpreter.evaluate(parse(`def work x
+    ensure (record "first")
+    missing-function x
+    ensure (record "second")`));
+
+    expect(() => interpreter.evaluate(parse('work 1'))).toThrow('Unknown function: missing-function');
+    expect(log).toEqual(['first']);
+  });
+
+  test('runs cleanup when each for callback returns', () => {
+    interpreter.evaluate(parse(`def visit-all items
+    for items {item: ensure (record item)}
+    record "end"`));
+
+    interpreter.evaluate(parse('visit-all ["a", "b"]'));
+    expect(log).toEqual(['a', 'b', 'end']);
+  });
+
+  test('runs cleanup in for callbacks at top level', () => {
+    interpreter.evaluate(parse('for ["a", "b"] {item: ensure (record item)}'));
+    expect(log).toEqual(['a', 'b']);
+  });
+
+  test('is rejected outside a function', () => {
+    expect(() => interpreter.evaluate(parse('ensure (record "closed")'))).toThrow('ensure can only be used inside a function');
+  });
+});
+
+describe('Strict mode', () => {
+  test('is off by default', () => {
+    const interpreter = new RelayInterpreter();
+    expect(interpreter.evaluate(parse('if null "yes" "no"'))).toBe('no');
+    expect(interpreter.evaluate(parse('get {"a": 1} "b"'))).toBe(undefined);
+    expect(interpreter.evaluate(parse('concat "count: " null'))).toBe('count: ');
+  });
+
+  test('requires boolean if conditions', () => {
+    const interpreter = new RelayInterpreter({ strict: true });
+    expect(interpreter.evaluate(parse('if (> 2 1) "yes" "no"'))).toBe('yes');
+    expect(() => interpreter.evaluate(parse('if null "yes" "no"'))).toThrow('if expects a boolean condition in strict mode, got: null');
+    expect(() => interpreter.evaluate(parse('if 1 "yes" "no"'))).toThrow('got: number');
+  });
+
+  test('rejects reading missing fields', () => {
+    const interpreter = new RelayInterpreter({ strict: true });
+    expect(interpreter.evaluate(parse('get {"a": 1} "a"'))).toBe(1);
+    expect(() => interpreter.evaluate(parse('get {"a": 1} "b"'))).toThrow('get found no field "b" (strict mode)');
+    expect(() => interpreter.evaluate(parse('get [1, 2] 5'))).toThrow('get found no field 5 (strict mode)');
+    expect(() => interpreter.evaluate(parse('get {"a": 1} "toString"'))).toThrow('get found no field "toString" (strict mode)');
+    expect(() => interpreter.evaluate(parse('get [1, 2] "length"'))).toThrow('get found no field "length" (strict mode)');
+  });
+
+  test('rejects implicit conversions in concat', () => {
+    const interpreter = new RelayInterpreter({ strict: true });
+    expect(interpreter.evaluate(parse('concat "count: " 3'))).toBe('count: 3');
+    expect(() => interpreter.evaluate(parse('concat "count: " null'))).toThrow('concat expects strings or numbers in strict mode, got: null');
+    expect(() => interpreter.evaluate(parse('concat "tags: " [1]'))).toThrow('got: list');
+  });
+
+  test('can be turned on with a pragma at the top of the program', () => {
+    const interpreter = new RelayInterpreter();
+    expect(() => interpreter.evaluate(parse(`"use strict"
+if null "yes" "no"`))).toThrow('if expects a boolean condition in strict mode');
+  });
+
+  test('only applies the pragma to the program that declares it', () => {
+    const interpreter = new RelayInterpreter();
+    expect(() => interpreter.evaluate(parse(`"use strict"
+if null "yes" "no"`))).toThrow('if expects a boolean condition in strict mode');
+    expect(interpreter.evaluate(parse('if null "yes" "no"'))).toBe('no');
+  });
+
+  test('keeps the constructor option after a program without the pragma', () => {
+    const interpreter = new RelayInterpreter({ strict: true });
+    interpreter.evaluate(parse('set x 1'));
+    expect(() => interpreter.evaluate(parse('if null "yes" "no"'))).toThrow('if expects a boolean condition in strict mode');
+  });
+
+  test('ignores the pragma anywhere but the first expression', () => {
+    const interpreter = new RelayInterpreter();
+    expect(interpreter.evaluate(parse(`set x 1
+"use strict"
+if null "yes" "no"`))).toBe('no');
+  });
+});
@@ -48,6 +48,25 @@ describe('RelayLexer', () => {
     expect(tokens[2]).toEqual({ type: 'NUMBER', value: 19.99, line: 1, column: 1 });
   });
 
+  test('tokenizes numbers with underscores, hex and exponents', () => {
+    const tokens = tokenize('1_000_000 0xFF -0x1_0 1e9 2.5E-3 -1.5e+2 3.25');
+    
+    expect(tokens.slice(0, 7).map(token => token.type)).toEqual(Array(7).fill('NUMBER'));
+    expect(tokens.slice(0, 7).map(token => token.value)).toEqual([1000000, 255, -16, 1e9, 0.0025, -150, 3.25]);
+  });
+
+  test('does not treat a unit suffix as an exponent', () => {
+    const tokens = tokenize('2em');
+    
+    expect(tokens[0]).toMatchObject({ type: 'NUMBER', value: 2 });
+    expect(tokens[1]).toMatchObject({ type: 'IDENTIFIER', value: 'em' });
+  });
+
+  test('rejects misplaced underscores in numbers', () => {
+    expect(() => tokenize('1__000')).toThrow('Invalid numeric literal 1_');
+    expect(() => tokenize('100_')).toThrow('Invalid numeric literal 100_');
+  });
+
   test('tokenizes operators', () => {
     const lexer = new RelayLexer('+ - * / %');
     const tokens = lexer.tokenize();
@@ -67,6 +86,42 @@ describe('RelayLexer', () => {
     expect(tokens[4]).toEqual({ type: 'RBRACKET', value: ']', line: 1, column: 1 });
   });
 
+  test('tokenizes strings mixing plain text and escapes', () => {
+    const lexer = new RelayLexer('"say \\"hi\\"\\tthen\\\\leave" "plain"');
+    const tokens = lexer.tokenize();
+    
+    expect(tokens[0].value).toBe('say "hi"\tthen\\leave');
+    expect(tokens[1].value).toBe('plain');
+  });
+
+  test('tokenizes unicode escapes in strings', () => {
+    const tokens = tokenize('"\\u{1F600} caf\\u00e9 \\u{41}"');
+    
+    expect(tokens[0].value).toBe('😀 café A');
+  });
+
+  test('rejects malformed unicode escapes', () => {
+    expect(() => tokenize('"\\u12"')).toThrow('Invalid unicode escape \\u12"');
+    expect(() => tokenize('"\\u{110000}"')).toThrow('code point out of range');
+    expect(() => tokenize('"\\u{zz}"')).toThrow('Invalid unicode escape \\u{zz}');
+  });
+
+  test('strings encoded with JSON.stringify round-trip through the lexer', () => {
+    const original = 'line\nbreak\t"quoted" back\\slash 😀 \u0001 \b\f/';
+    const tokens = tokenize(JSON.stringify(original));
+    
+    expect(tokens[0].value).toBe(original);
+  });
+
+  test('tokenizes non-ASCII identifiers', () => {
+    const lexer = new RelayLexer('set título "café"');
+    const tokens = lexer.tokenize();
+    
+    expect(tokens[1].type).toBe('IDENTIFIER');
+    expect(tokens[1].value).toBe('título');
+    expect(tokens[2].value).toBe('café');
+  });
+
   test('handles indentation', () => {
     const code = `if user
     show card`;
@@ -299,6 +354,44 @@ set x 1 # Another comment`;
     expect(ifExpr.args).toHaveLength(3);
   });
 
+  test('flattens chained comparisons into one call', () => {
+    const ast = parse('0 < x < 10');
+    expect(ast.expressions).toHaveLength(1);
+
+    const expr = ast.expressions[0] as FuncallNode;
+    expect(expr.name).toBe('<');
+    expect(expr.args).toHaveLength(3);
+    expect(expr.args[0]).toEqual({ type: 'atom', value: 0 });
+    expect(expr.args[2]).toEqual({ type: 'atom', value: 10 });
+  });
+
+  test('keeps mixed infix operators nested', () => {
+    const expr = parse('x < y > z').expressions[0] as FuncallNode;
+    expect(expr.name).toBe('<');
+    expect(expr.args).toHaveLength(2);
+    expect((expr.args[1] as FuncallNode).name).toBe('>');
+  });
+
+  test('parses single-line if then else as a plain if call', () => {
+    const expr = parse('if ok then "yes" else "no"').expressions[0] as FuncallNode;
+    expect(expr.name).toBe('if');
+    expect(expr.args).toEqual([
+      { type: 'identifier', name: 'ok' },
+      { type: 'atom', value: 'yes' },
+      { type: 'atom', value: 'no' }
+    ]);
+  });
+
+  test('parses an infix condition in single-line if', () => {
+    const expr = parse('if x > 0 then "a" else "b"').expressions[0] as FuncallNode;
+    expect(expr.name).toBe('if');
+    expect(expr.args).toEqual([
+      { type: 'funcall', name: '>', args: [{ type: 'identifier', name: 'x' }, { type: 'atom', value: 0 }] },
+      { type: 'atom', value: 'a' },
+      { type: 'atom', value: 'b' }
+    ]);
+  });
+
   test('handles empty input', () => {
     const ast = parse('');
     expect(ast.type).toBe('program');
@@ -350,6 +443,76 @@ describe('Error handling', () => {
   });
 });
 
+describe('Parse limits', () => {
+  test('rejects programs longer than maxSourceLength', () => {
+    expect(() => {
+      parse('show heading "hello"', { maxSourceLength: 10 });
+    }).toThrow('Program is 20 characters, larger than the limit of 10');
+  });
+
+  test('rejects programs with more expressions than maxNodes', () => {
+    const source = '[' + Array.from({ length: 50 }, (_, i) => i).join(', ') + ']';
+    
+    expect(parse(source, { maxNodes: 100 }).expressions).toHaveLength(1);
+    expect(() => {
+      parse(source, { maxNodes: 20 });
+    }).toThrow('Program exceeds the limit of 20 expressions');
+  });
+
+  test('counts every inline argument against maxNodes', () => {
+    const source = 'show x' + ' "a"'.repeat(50);
+    
+    expect(parse(source, { maxNodes: 100 }).expressions).toHaveLength(1);
+    expect(() => {
+      parse(source, { maxNodes: 20 });
+    }).toThrow('Program exceeds the limit of 20 expressions');
+    expect(() => {
+      parse('x + 1' + ' 1'.repeat(50), { maxNodes: 20 });
+    }).toThrow('Program exceeds the limit of 20 expressions');
+  });
+
+  test('rejects nesting deeper than maxDepth', () => {
+    expect(parse('[[1]]', { maxDepth: 3 }).expressions).toHaveLength(1);
+    expect(() => {
+      parse('[[[1]]]', { maxDepth: 3 });
+    }).toThrow('Expression nesting exceeds 3 levels');
+  });
+
+  test('stops parsing after timeoutMs', () => {
+    const source = Array.from({ length: 2000 }, () => 'show heading "hello"').join('\n');
+    
+    expect(() => {
+      parse(source, { timeoutMs: -1 });
+    }).toThrow('Parsing exceeded the time limit of -1ms');
+  });
+
+  test('applies timeoutMs to tokenizing', () => {
+    const source = 'show x' + ' "a"'.repeat(2000);
+    
+    expect(() => {
+      tokenize(source, { timeoutMs: -1 });
+    }).toThrow('Parsing exceeded the time limit of -1ms');
+  });
+
+  test('reads the clock only when timeoutMs is set', () => {
+    const source = Array.from({ length: 2000 }, () => 'show heading "hello"').join('\n');
+    const now = jest.spyOn(Date, 'now');
+
+    try {
+      parse(source);
+      const untimed = now.mock.calls.length;
+      parse(source, { timeoutMs: 60000 });
+      const timed = now.mock.calls.length - untimed;
+
+      expect(untimed).toBeLessThanOrEqual(1);
+      expect(timed).toBeGreaterThan(1);
+      expect(timed).toBeLessThan(100);
+    } finally {
+      now.mockRestore();
+    }
+  });
+});
+
 describe('Edge cases', () => {
   test('handles identifiers with question marks', () => {
     const ast = parse('is_even? 42');
@@ -0,0 +1,100 @@
+import { parse } from '../parser';
+import { RelayInterpreter } from '../interpreter';
+import { createRandom } from '../testing';
+
+// Check a property against generated cases, reporting the first counterexample
+function forAll<T>(seed: number, runs: number, generate: (random: () => number) => T, property: (value: T) => void): void {
+  const random = createRandom(seed);
+  for (let i = 0; i < runs; i++) {
+    const value = generate(random);
+    try {
+      property(value);
+    } catch (error) {
+      const message = error instanceof Error ? error.message : String(error);
+      throw new Error(`Property failed for ${JSON.stringify(value)} (seed ${seed}, run ${i}): ${message}`);
+    }
+  }
+}
+
+const integer = (random: () => number) => Math.floor(random() * 20001) - 10000;
+const decimal = (random: () => number) => Math.round((random() * 2000 - 1000) * 100) / 100;
+const number = (random: () => number) => (random() < 0.5 ? integer(random) : decimal(random));
+const primitive = (random: () => number): any => {
+  switch (Math.floor(random() * 4)) {
+    case 0: return number(random);
+    case 1: return `s${integer(random)}`;
+    case 2: return random() < 0.5;
+    default: return null;
+  }
+};
+
+// Render a value as Relay source; JSON literals are valid Relay
+const literal = (value: any) => JSON.stringify(value);
+
+describe('Evaluator properties', () => {
+  let interpreter: RelayInterpreter;
+
+  const run = (source: string) => interpreter.evaluate(parse(source));
+
+  beforeEach(() => {
+    interpreter = new RelayInterpreter();
+  });
+
+  test('arithmetic matches JavaScript number semantics', () => {
+    forAll(4953, 300, random => [number(random), number(random), number(random)], ([a, b, c]) => {
+      expect(run(`+ ${a} ${b} ${c}`)).toBe(a + b + c);
+      expect(run(`- ${a} ${b} ${c}`)).toBe(a - b - c);
+      expect(run(`* ${a} ${b} ${c}`)).toBe(a * b * c);
+      if (b !== 0) {
+        expect(run(`/ ${a} ${b}`)).toBe(a / b);
+        expect(run(`% ${a} ${b}`)).toBe(a % b);
+      }
+    });
+  });
+
+  test('addition and multiplication are commutative', () => {
+    forAll(1, 300, random => [integer(random), integer(random)], ([a, b]) => {
+      expect(run(`+ ${a} ${b}`)).toBe(run(`+ ${b} ${a}`));
+      expect(run(`* ${a} ${b}`)).toBe(run(`* ${b} ${a}`));
+    });
+  });
+
+  test('equal is reflexive and symmetric', () => {
+    forAll(2, 300, random => [primitive(random), primitive(random)], ([a, b]) => {
+      expect(run(`equal ${literal(a)} ${literal(a)}`)).toBe(true);
+      expect(run(`equal ${literal(a)} ${literal(b)}`)).toBe(run(`equal ${literal(b)} ${literal(a)}`));
+    });
+  });
+
+  test('< and > are converses of each other', () => {
+    forAll(3, 300, random => [number(random), number(random)], ([a, b]) => {
+      expect(run(`< ${a} ${b}`)).toBe(run(`> ${b} ${a}`));
+      if (run(`< ${a} ${b}`)) {
+        expect(run(`> ${a} ${b}`)).toBe(false);
+      }
+    });
+  });
+
+  test('calling a lambda matches evaluating its body with the argument substituted', () => {
+    forAll(4, 200, random => [integer(random), integer(random)], ([a, n]) => {
+      interpreter.evaluate(parse(`set offset ${n}`));
+      interpreter.evaluate(parse('set shift {x: + x offset}'));
+      expect(run(`shift ${a}`)).toBe(run(`+ ${a} ${n}`));
+    });
+  });
+
+  test('if picks the branch matching the truthiness of its condition', () => {
+    forAll(5, 300, primitive, value => {
+      const truthy = value !== false && value !== null && value !== 0;
+      expect(run(`if ${literal(value)} "then" "else"`)).toBe(truthy ? 'then' : 'else');
+    });
+  });
+
+  test('add appends without mutating the original list', () => {
+    forAll(6, 200, random => [Array.from({ length: Math.floor(random() * 5) }, () => primitive(random)), primitive(random)], ([list, item]) => {
+      interpreter.evaluate(parse(`set items ${literal(list)}`));
+      expect(run(`add items ${literal(item)}`)).toEqual([...list, item]);
+      expect(run('items')).toEqual(list);
+    });
+  });
+});
@@ -0,0 +1,225 @@
+import { parse } from '../parser';
+import { RelayInterpreter } from '../interpreter';
+import {
+  walkValue,
+  deepCopy,
+  freezeValue,
+  isFrozenValue,
+  canonicalString,
+  hashValue,
+  diffValues,
+  formatDiff,
+  ValuePath
+} from '../values';
+
+describe('Value utilities', () => {
+  const sample = () => ({
+    title: 'Posts',
+    posts: [
+      { id: 1, tags: ['relay', 'p2p'] },
+      { id: 2, tags: [] }
+    ],
+    draft: null
+  });
+
+  describe('walkValue', () => {
+    test('visits every value with its path, parents first', () => {
+      const paths: ValuePath[] = [];
+      walkValue({ a: [1, { b: 2 }] }, (_value, path) => {
+        paths.push(path);
+      });
+      
+      expect(paths).toEqual([[], ['a'], ['a', 0], ['a', 1], ['a', 1, 'b']]);
+    });
+
+    test('skips children when the visitor returns false', () => {
+      const visited: any[] = [];
+      walkValue(sample(), (value, path) => {
+        visited.push(path.join('.'));
+        return path[0] !== 'posts';
+      });
+      
+      expect(visited).toEqual(['', 'title', 'posts', 'draft']);
+    });
+
+    test('does not traverse into function closures', () => {
+      const interpreter = new RelayInterpreter();
+      const fn = interpreter.evaluate(parse('set double {x: * x 2}'));
+      let visits = 0;
+      walkValue({ fn }, () => {
+        visits++;
+      });
+      
+      expect(visits).toBe(2);
+    });
+  });
+
+  describe('deepCopy', () => {
+    test('copies nested lists and objects', () => {
+      const original = sample();
+      const copy = deepCopy(original);
+      
+      expect(copy).toEqual(original);
+      expect(copy.posts).not.toBe(original.posts);
+      expect(copy.posts[0].tags).not.toBe(original.posts[0].tags);
+    });
+
+    test('returns primitives unchanged', () => {
+      expect(deepCopy(42)).toBe(42);
+      expect(deepCopy('text')).toBe('text');
+      expect(deepCopy(null)).toBe(null);
+    });
+  });
+
+  describe('freezeValue', () => {
+    test('freezes every nested list and object', () => {
+      const value = freezeValue(sample());
+      
+      expect(isFrozenValue(value)).toBe(true);
+      expect(Object.isFrozen(value.posts[1].tags)).toBe(true);
+      expect(() => {
+        (value.posts as any[]).push({ id: 3 });
+      }).toThrow();
+    });
+
+    test('reports partially frozen values as not frozen', () => {
+      const value = sample();
+      Object.freeze(value);
+      
+      expect(isFrozenValue(value)).toBe(false);
+    });
+
+    test('copies of frozen values are mutable', () => {
+      const copy = deepCopy(freezeValue(sample()));
+      copy.posts.push({ id: 3, tags: [] });
+      
+      expect(copy.posts).toHaveLength(3);
+    });
+  });
+
+  describe('canonicalString and hashValue', () => {
+    test('ignores object field order', () => {
+      const a = { id: 1, user: { name: 'Ana', roles: ['admin'] } };
+      const b = { user: { roles: ['admin'], name: 'Ana' }, id: 1 };
+      
+      expect(canonicalString(a)).toBe(canonicalString(b));
+      expect(hashValue(a)).toBe(hashValue(b));
+    });
+
+    test('distinguishes different values', () => {
+      const values = [null, 0, '0', false, [], {}, [0], { a: 0 }, { a: '0' }, 'null', [null]];
+      const hashes = new Set(values.map(value => hashValue(value)));
+      
+      expect(hashes.size).toBe(values.length);
+    });
+
+    test('respects list order', () => {
+      expect(hashValue([1, 2])).not.toBe(hashValue([2, 1]));
+    });
+
+    test('produces fixed-width hex hashes', () => {
+      expect(hashValue({ title: 'Posts' })).toMatch(/^[0-9a-f]{14}$/);
+      expect(canonicalString({ b: [1, -0], a: 'x' })).toBe('{"a":"x","b":[1,0]}');
+    });
+
+    test('is available to Relay code through the hash builtin', () => {
+      const interpreter = new RelayInterpreter();
+      const result = interpreter.evaluate(parse('equal (hash {"a": 1, "b": 2}) (hash {"b": 2, "a": 1})'));
+      
+      expect(result).toBe(true);
+    });
+
+    test('rejects functions', () => {
+      const interpreter = new RelayInterpreter();
+      
+      expect(() => {
+        interpreter.evaluate(parse('hash {x: x}'));
+      }).toThrow('Cannot canonicalize functions');
+    });
+  });
+
+  describe('diffValues and formatDiff', () => {
+    test('reports nothing for structurally equal values', () => {
+      expect(diffValues({ a: [1, { b: 2 }] }, { a: [1, { b: 2 }] })).toEqual([]);
+    });
+
+    test('reports missing, extra and changed fields', () => {
+      const changes = diffValues(
+        { id: 1, title: 'Old', draft: true },
+        { id: 1, title: 'New', tags: [] }
+      );
+      
+      expect(changes).toEqual([
+        { kind: 'removed', path: ['draft'], before: true },
+        { kind: 'changed', path: ['title'], before: 'Old', after: 'New' },
+        { kind: 'added', path: ['tags'], after: [] }
+      ]);
+    });
+
+    test('reports list insertions and removals rather than shifted items', () => {
+      expect(diffValues(['a', 'b', 'c'], ['a', 'x', 'b', 'c'])).toEqual([
+        { kind: 'added', path: [1], after: 'x' }
+      ]);
+      expect(diffValues(['a', 'b', 'c'], ['a', 'c'])).toEqual([
+        { kind: 'removed', path: [1], before: 'b' }
+      ]);
+    });
+
+    test('diffs into list items that were edited in place', () => {
+      const changes = diffValues(
+        { posts: [{ id: 1, title: 'A' }, { id: 2, title: 'B' }] },
+        { posts: [{ id: 1, title: 'A' }, { id: 2, title: 'B2' }] }
+      );
+      
+      expect(changes).toEqual([
+        { kind: 'changed', path: ['posts', 1, 'title'], before: 'B', after: 'B2' }
+      ]);
+    });
+
+    test('treats inherited members as missing fields', () => {
+      expect(diffValues({}, { toString: 1 })).toEqual([
+        { kind: 'added', path: ['toString'], after: 1 }
+      ]);
+      expect(diffValues({ a: 1, constructor: 2 }, { a: 1 })).toEqual([
+        { kind: 'removed', path: ['constructor'], before: 2 }
+      ]);
+    });
+
+    test('diffs long lists of objects', () => {
+      const before = Array.from({ length: 1000 }, (_, i) => ({ id: i, tags: ['a', 'b'] }));
+      const after = [...before.slice(0, 500), { id: -1, tags: [] }, ...before.slice(500)];
+      
+      expect(diffValues(before, after)).toEqual([
+        { kind: 'added', path: [500], after: { id: -1, tags: [] } }
+      ]);
+    });
+
+    test('matches list items holding functions by identity', () => {
+      const handler = { type: 'function', params: [], body: { type: 'atom', value: null }, closure: { bindings: new Map() } };
+      
+      expect(diffValues([{ on: handler }, 1], [{ on: handler }, 2])).toEqual([
+        { kind: 'changed', path: [1], before: 1, after: 2 }
+      ]);
+    });
+
+    test('formats changes one per line', () => {
+      const text = formatDiff(diffValues(
+        { user: { name: 'Ana' }, 'odd key': 1, tags: ['x'] },
+        { user: { name: 'Bo' }, tags: ['x', 'y'] }
+      ));
+      
+      expect(text).toBe([
+        '- ["odd key"]: 1',
+        '~ user.name: "Ana" -> "Bo"',
+        '+ tags[1]: "y"'
+      ].join('\n'));
+    });
+
+    test('is available to Relay code through the diff builtin', () => {
+      const interpreter = new RelayInterpreter();
+      const result = interpreter.evaluate(parse('diff {"count": 1} {"count": 2}'));
+      
+      expect(result).toEqual([{ kind: 'changed', path: ['count'], before: 1, after: 2 }]);
+    });
+  });
+});
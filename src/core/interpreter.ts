@@ -12,6 +12,7 @@ import {
   JsonObjectNode, 
   IdentifierNode 
 } from './parser';
+import { hashValue, redactValue, projectValue, diffValues, getIn, setIn, sameValue, hasOwnField, REDACTED } from './values';
 
 // Environment for variable and function scoping
 export interface Environment {
@@ -63,6 +64,43 @@ export function lookupVariable(name: string, env: Environment): any {
   throw new Error(`Undefined variable: ${name}`);
 }
 
+// Find a binding in the environment chain without throwing when it is missing
+export function findBinding(name: string, env: Environment): any {
+  for (let current: Environment | undefined = env; current; current = current.parent) {
+    if (current.bindings.has(name)) {
+      return current.bindings.get(name);
+    }
+  }
+  return undefined;
+}
+
+// Check whether the nearest binding of a name was declared with const, so parameters and locals can shadow constants
+export function isConstant(name: string, env: Environment): boolean {
+  for (let current: Environment | undefined = env; current; current = current.parent) {
+    if (current.bindings.has(name)) {
+      const constants: Set<string> | undefined = current.bindings.get('_constants');
+      return constants?.has(name) ?? false;
+    }
+  }
+  return false;
+}
+
+// Reject definitions that would replace a constant
+function expectNotConstant(name: string, env: Environment): void {
+  if (isConstant(name, env)) {
+    throw new Error(`Cannot reassign constant ${name}`);
+  }
+}
+
+// Mask redacted variables and object fields before a value is logged
+export function loggableValue(value: any, env: Environment, name?: string): any {
+  const fields: Set<string> | undefined = findBinding('_redactedFields', env);
+  if (!fields) {
+    return value;
+  }
+  return name !== undefined && fields.has(name) ? REDACTED : redactValue(value, fields);
+}
+
 // Set a variable in the current environment
 export function setVariable(name: string, value: any, env: Environment): void {
   env.bindings.set(name, value);
@@ -86,15 +124,237 @@ export function createComponent(name: string, props: Record<string, any> = {}, c
   };
 }
 
+// In Relay, any non-false, non-null, non-zero value is truthy
+export function isTruthy(value: any): boolean {
+  return value !== false && value !== null && value !== 0;
+}
+
+// Round to a number of decimal places, halves away from zero. Shifting through the
+// decimal exponent avoids binary artifacts like 1.005 * 100 = 100.49999999999999
+export function roundDecimal(value: number, places: number): number {
+  if (!Number.isFinite(value) || String(value).includes('e')) {
+    return Number(value.toFixed(places));
+  }
+  const sign = value < 0 ? -1 : 1;
+  const shifted = Math.round(Number(`${Math.abs(value)}e${places}`));
+  if (String(shifted).includes('e')) {
+    // From 1e21 up the shifted value prints as "1e+21", which cannot take another exponent.
+    // Numbers that large have no fractional digits left to round anyway
+    return Number(value.toFixed(places));
+  }
+  return sign * Number(`${shifted}e-${places}`);
+}
+
+// Order values of different types: null < booleans < numbers < strings < lists < objects
+function typeRank(value: any): number {
+  if (value === null || value === undefined) return 0;
+  if (typeof value === 'boolean') return 1;
+  if (typeof value === 'number') return 2;
+  if (typeof value === 'string') return 3;
+  if (Array.isArray(value)) return 4;
+  return 5;
+}
+
+// Compare strings by Unicode code point. Plain < compares UTF-16 units, which puts
+// astral characters like emoji before U+E000-U+FFFF
+export function compareCodePoints(a: string, b: string): number {
+  let i = 0;
+  while (i < a.length && i < b.length) {
+    const codeA = a.codePointAt(i)!;
+    const codeB = b.codePointAt(i)!;
+    if (codeA !== codeB) {
+      return codeA < codeB ? -1 : 1;
+    }
+    i += codeA > 0xffff ? 2 : 1;
+  }
+  return Math.sign(a.length - b.length);
+}
+
+// Three-way comparison used for sorting; strings use the collator when one is given
+export function compareValues(a: any, b: any, collator?: Intl.Collator): number {
+  const rankA = typeRank(a);
+  const rankB = typeRank(b);
+  if (rankA !== rankB) {
+    return rankA < rankB ? -1 : 1;
+  }
+
+  if (typeof a === 'string' && collator) {
+    return Math.sign(collator.compare(a, b));
+  }
+
+  if (Array.isArray(a)) {
+    for (let i = 0; i < Math.min(a.length, b.length); i++) {
+      const result = compareValues(a[i], b[i], collator);
+      if (result !== 0) return result;
+    }
+    return Math.sign(a.length - b.length);
+  }
+
+  if (rankA === 5) {
+    return compareObjects(a, b, collator);
+  }
+
+  // Nulls are all equal
+  if (rankA === 0 || a === b) {
+    return 0;
+  }
+  if (typeof a === 'number' && (Number.isNaN(a) || Number.isNaN(b))) {
+    // NaN (e.g. from % 5 0) equals itself, as in equals, and sorts after every other number
+    return Number.isNaN(a) === Number.isNaN(b) ? 0 : Number.isNaN(a) ? 1 : -1;
+  }
+  if (typeof a === 'string') {
+    return compareCodePoints(a, b);
+  }
+  return a < b ? -1 : 1;
+}
+
+// Objects order canonically, so the result is 0 exactly when equals would be true:
+// first by their sorted field names, then by the values of those fields.
+// Functions have no order and are rejected
+function compareObjects(a: any, b: any, collator?: Intl.Collator): number {
+  if (isRelayFunction(a) || isRelayFunction(b)) {
+    if (a === b) return 0;
+    throw new Error("Cannot order functions");
+  }
+
+  const fieldsOf = (value: any) => Object.keys(value).filter(key => value[key] !== undefined).sort(compareCodePoints);
+  const fieldsA = fieldsOf(a);
+  const fieldsB = fieldsOf(b);
+
+  const names = compareValues(fieldsA, fieldsB);
+  if (names !== 0) return names;
+
+  for (const field of fieldsA) {
+    const result = compareValues(a[field], b[field], collator);
+    if (result !== 0) return result;
+  }
+  return 0;
+}
+
+// Ordering only makes sense between values of one kind. In strict mode mixed operands,
+// like a null count or the boolean from an unchained `0 < x < 10`, are reported; otherwise
+// they compare as JavaScript would, so (> count 0) with a null count is false
+function expectComparable(name: string, args: any[], env: Environment): void {
+  if (args.length < 2) {
+    throw new Error(`${name} expects at least 2 arguments`);
+  }
+
+  if (!isStrict(env)) {
+    return;
+  }
+
+  const kind = typeof args[0];
+  if ((kind !== 'number' && kind !== 'string') || args.some(arg => typeof arg !== kind)) {
+    const types = args.map(arg => (arg === null ? 'null' : typeof arg)).join(', ');
+    throw new Error(`${name} expects all numbers or all strings, got: ${types}`);
+  }
+}
+
+// Check whether a runtime value is a Relay function
+export function isRelayFunction(value: any): value is RelayFunction {
+  return !!value && typeof value === 'object' && value.type === 'function';
+}
+
+// Name of a value's runtime type as reported by type-of
+export function typeName(value: any): string {
+  if (value === null || value === undefined) return 'null';
+  if (Array.isArray(value)) return 'list';
+  if (isRelayFunction(value)) return 'function';
+  if (typeof value === 'object') return value.type === 'component' ? 'component' : 'object';
+  return typeof value; // number, string or boolean
+}
+
+// Split text into lowercase search terms
+export function searchTerms(text: string): string[] {
+  return text.toLowerCase().split(/[^\p{L}\p{N}]+/u).filter(term => term.length > 0);
+}
+
+// Call a Relay function with already evaluated argument values (used by builtins taking callbacks)
+export function applyFunction(
+  func: RelayFunction,
+  values: any[],
+  evaluate: (expr: ExpressionNode, env: Environment) => any
+): any {
+  const callEnv = createEnvironment(func.closure);
+
+  // Extra values are dropped so callbacks can ignore trailing arguments like the index
+  for (let i = 0; i < func.params.length; i++) {
+    setVariable(func.params[i], values[i], callEnv);
+  }
+
+  return runWithEnsure(callEnv, () => evaluate(func.body, callEnv), evaluate);
+}
+
+// Cleanup registered by ensure, with the environment it was registered in
+interface EnsureBlock {
+  body: ExpressionNode[];
+  env: Environment;
+}
+
+// Run a function body, then its ensure blocks in reverse registration order whether the
+// body returned or threw. The body's error wins over cleanup errors; otherwise the first
+// cleanup error is thrown once every block has run
+export function runWithEnsure(
+  callEnv: Environment,
+  body: () => any,
+  evaluate: (expr: ExpressionNode, env: Environment) => any
+): any {
+  const blocks: EnsureBlock[] = [];
+  setVariable('_ensureBlocks', blocks, callEnv);
+
+  const runBlocks = (): unknown => {
+    let firstError: unknown = undefined;
+    for (const block of blocks.reverse()) {
+      try {
+        block.body.forEach(expr => evaluate(expr, block.env));
+      } catch (error) {
+        firstError = firstError ?? error;
+      }
+    }
+    return firstError;
+  };
+
+  let result: any;
+  try {
+    result = body();
+  } catch (error) {
+    runBlocks();
+    throw error;
+  }
+
+  const cleanupError = runBlocks();
+  if (cleanupError !== undefined) {
+    throw cleanupError;
+  }
+  return result;
+}
+
+// Pragma that turns on strict mode when it is the first expression of a program
+export const STRICT_PRAGMA = 'use strict';
+
+export interface InterpreterOptions {
+  // Strict mode: if conditions must be booleans, get fails on missing fields, concat
+  // only joins strings and numbers, and < > between only compare values of one kind
+  strict?: boolean;
+}
+
+// Check whether strict mode is on for the program this environment belongs to
+export function isStrict(env: Environment): boolean {
+  return findBinding('_strict', env) === true;
+}
+
 // Main interpreter class
 export class RelayInterpreter {
   private globalEnv: Environment;
   private componentCollection: RenderableComponent[] = [];
   private isEvaluatingChildren: boolean = false;
+  private strict: boolean;
 
-  constructor() {
+  constructor(options: InterpreterOptions = {}) {
     this.globalEnv = createEnvironment();
     this.componentCollection = [];
+    this.strict = options.strict === true;
+    this.globalEnv.bindings.set('_strict', this.strict);
     this.setupBuiltins();
   }
 
@@ -123,8 +383,17 @@ export class RelayInterpreter {
     
     let lastResult = null;
     
-    for (const expression of program.expressions) {
-      lastResult = this.evaluateExpression(expression, this.globalEnv);
+    // The pragma only applies to this program; later ones fall back to the constructor option
+    const [first] = program.expressions;
+    const hasPragma = !!first && first.type === 'atom' && (first as AtomNode).value === STRICT_PRAGMA;
+    this.globalEnv.bindings.set('_strict', this.strict || hasPragma);
+    
+    try {
+      for (const expression of program.expressions) {
+        lastResult = this.evaluateExpression(expression, this.globalEnv);
+      }
+    } finally {
+      this.globalEnv.bindings.set('_strict', this.strict);
     }
     
     // Extract event handlers from global environment
@@ -251,8 +520,9 @@ export class RelayInterpreter {
       setVariable(func.params[i], paramValue, callEnv);
     }
     
-    // Evaluate function body
-    return this.evaluateExpression(func.body, callEnv);
+    // Evaluate function body, then any cleanup it registered with ensure
+    const evaluate = (expr: ExpressionNode, evalEnv: Environment) => this.evaluateExpression(expr, evalEnv);
+    return runWithEnsure(callEnv, () => evaluate(func.body, callEnv), evaluate);
   }
 
   // Evaluate sequence blocks
@@ -320,14 +590,99 @@ export class RelayInterpreter {
       
       const condition = evaluate(args[0], env);
       
-      // In Relay, any non-false, non-null value is truthy
-      if (condition !== false && condition !== null && condition !== 0) {
+      if (isStrict(env) && typeof condition !== 'boolean') {
+        throw new Error(`if expects a boolean condition in strict mode, got: ${typeName(condition)}`);
+      }
+      
+      if (isTruthy(condition)) {
         return evaluate(args[1], env);  // evaluate then branch
       } else {
         return evaluate(args[2], env);  // evaluate else branch
       }
     });
 
+    // Logical and: returns the first falsy operand, or the last one (LAZY - short-circuits)
+    defineBuiltin("and", false, (args: ExpressionNode[], env: Environment, evaluate) => {
+      if (args.length === 0) {
+        throw new Error("and expects at least 1 argument");
+      }
+
+      let value: any = null;
+      for (const arg of args) {
+        value = evaluate(arg, env);
+        if (!isTruthy(value)) {
+          return value;
+        }
+      }
+      return value;
+    });
+
+    // Logical or: returns the first truthy operand, or the last one, so
+    // `or name "anonymous"` works as a default (LAZY - short-circuits)
+    defineBuiltin("or", false, (args: ExpressionNode[], env: Environment, evaluate) => {
+      if (args.length === 0) {
+        throw new Error("or expects at least 1 argument");
+      }
+
+      let value: any = null;
+      for (const arg of args) {
+        value = evaluate(arg, env);
+        if (isTruthy(value)) {
+          return value;
+        }
+      }
+      return value;
+    });
+
+    // Type-of function naming a value's type: number, string, boolean, null, list, object, function (EAGER)
+    defineBuiltin("type-of", true, (args: any[]) => {
+      if (args.length !== 1) {
+        throw new Error("type-of expects exactly 1 argument");
+      }
+      return typeName(args[0]);
+    });
+
+    // Match-type function branching on a value's type (LAZY - needs evaluate to call the handler)
+    // Cases map type names to handlers called with the value; "_" matches anything else:
+    //   match-type params {"number": {n: * n 2}, "string": {s: concat s "!"}, "_": null}
+    // Cases that are not functions are returned as they are
+    defineBuiltin("match-type", false, (args: ExpressionNode[], env: Environment, evaluate) => {
+      if (args.length !== 2) {
+        throw new Error("match-type expects exactly 2 arguments: value and cases object");
+      }
+
+      const value = evaluate(args[0], env);
+      const cases = evaluate(args[1], env);
+
+      if (typeName(cases) !== 'object') {
+        throw new Error("match-type expects second argument to be an object of cases, got: " + typeName(cases));
+      }
+
+      const type = typeName(value);
+      const handler = type in cases ? cases[type] : cases['_'];
+      if (handler === undefined) {
+        throw new Error(`match-type has no case for type ${type}`);
+      }
+
+      return isRelayFunction(handler) ? applyFunction(handler, [value], evaluate) : handler;
+    });
+
+    // Ensure function registering cleanup that runs when the enclosing function exits,
+    // even if it fails; later blocks run first (LAZY - body is deferred)
+    defineBuiltin("ensure", false, (args: ExpressionNode[], env: Environment) => {
+      if (args.length === 0) {
+        throw new Error("ensure expects a cleanup expression or block");
+      }
+
+      const blocks: EnsureBlock[] | undefined = findBinding('_ensureBlocks', env);
+      if (!blocks) {
+        throw new Error("ensure can only be used inside a function");
+      }
+
+      blocks.push({ body: args, env });
+      return null;
+    });
+
     // Arithmetic operations (EAGER - all arguments pre-evaluated)
     defineBuiltin("+", true, (args: any[]) => {
       return args.reduce((sum, arg) => {
@@ -400,20 +755,64 @@ export class RelayInterpreter {
       return args[0] === args[1];
     });
 
-    defineBuiltin("<", true, (args: any[]) => {
+    // Equals function for deep structural equality of lists and objects (EAGER)
+    defineBuiltin("equals", true, (args: any[]) => {
       if (args.length !== 2) {
-        throw new Error("< expects exactly 2 arguments");
+        throw new Error("equals expects exactly 2 arguments");
       }
-      
-      return args[0] < args[1];
+
+      return sameValue(args[0], args[1]);
     });
 
-    defineBuiltin(">", true, (args: any[]) => {
+    // Approx-equals function for comparing floats within a tolerance: approx-equals x y [epsilon] (EAGER)
+    defineBuiltin("approx-equals", true, (args: any[]) => {
+      if (args.length !== 2 && args.length !== 3) {
+        throw new Error("approx-equals expects 2 or 3 arguments: x, y and optional epsilon");
+      }
+
+      const [x, y, epsilon = 1e-9] = args;
+
+      if (typeof x !== 'number' || typeof y !== 'number') {
+        throw new Error("approx-equals expects numbers to compare, got: " + typeof x + ", " + typeof y);
+      }
+
+      if (typeof epsilon !== 'number' || epsilon < 0) {
+        throw new Error("approx-equals expects epsilon to be a non-negative number");
+      }
+
+      return x === y || Math.abs(x - y) <= epsilon;
+    });
+
+    // Compare function for three-way comparison, returning -1, 0 or 1 in the order sort-by uses.
+    // Objects order by field names then values, so compare is 0 exactly when equals is true (EAGER)
+    defineBuiltin("compare", true, (args: any[]) => {
       if (args.length !== 2) {
-        throw new Error("> expects exactly 2 arguments");
+        throw new Error("compare expects exactly 2 arguments");
       }
-      
-      return args[0] > args[1];
+
+      return compareValues(args[0], args[1]);
+    });
+
+    // Chained comparisons: < 0 x 10 means 0 < x and x < 10 (and `0 < x < 10` parses to it)
+    defineBuiltin("<", true, (args: any[], env: Environment) => {
+      expectComparable("<", args, env);
+      return args.every((arg, i) => i === 0 || args[i - 1] < arg);
+    });
+
+    defineBuiltin(">", true, (args: any[], env: Environment) => {
+      expectComparable(">", args, env);
+      return args.every((arg, i) => i === 0 || args[i - 1] > arg);
+    });
+
+    // Between function for inclusive range checks: between x low high (EAGER)
+    defineBuiltin("between", true, (args: any[], env: Environment) => {
+      if (args.length !== 3) {
+        throw new Error("between expects exactly 3 arguments: value, low and high");
+      }
+      expectComparable("between", args, env);
+
+      const [value, low, high] = args;
+      return low <= value && value <= high;
     });
 
     // Function definition: def name params body (LAZY - controls argument evaluation)
@@ -429,6 +828,7 @@ export class RelayInterpreter {
       }
       
       const name = (nameArg as IdentifierNode).name;
+      expectNotConstant(name, env);
       
       // Don't evaluate params or body - store them as AST nodes
       const params = args[1];
@@ -592,7 +992,7 @@ export class RelayInterpreter {
       interpreterInstance.addComponent(component);
       
       // Also log for debugging
-      console.log(`[SHOW] ${componentName}:`, { props, children: children.length });
+      console.log(`[SHOW] ${componentName}:`, { props: loggableValue(props, env), children: children.length });
       
       return component;
     });
@@ -650,7 +1050,7 @@ export class RelayInterpreter {
     });
 
     // Get function for object property access (EAGER)
-    defineBuiltin("get", true, (args: any[]) => {
+    defineBuiltin("get", true, (args: any[], env: Environment) => {
       if (args.length !== 2) {
         throw new Error("get expects exactly 2 arguments: object and key");
       }
@@ -666,15 +1066,54 @@ export class RelayInterpreter {
         throw new Error("get expects second argument to be a string key or number index, got: " + typeof key);
       }
       
+      if (isStrict(env) && !hasOwnField(obj, key)) {
+        throw new Error(`get found no field ${JSON.stringify(key)} (strict mode)`);
+      }
+      
       return obj[key];
     });
 
+    // Get-in function for nil-safe access to nested fields (EAGER)
+    defineBuiltin("get-in", true, (args: any[]) => {
+      if (args.length !== 2) {
+        throw new Error("get-in expects exactly 2 arguments: value and path");
+      }
+
+      const [value, path] = args;
+      if (!Array.isArray(path) || path.some(step => typeof step !== 'string' && typeof step !== 'number')) {
+        throw new Error("get-in expects second argument to be a list of keys and indexes");
+      }
+
+      return getIn(value, path);
+    });
+
+    // Set-in function for updating nested fields without mutating the original (EAGER)
+    defineBuiltin("set-in", true, (args: any[]) => {
+      if (args.length !== 3) {
+        throw new Error("set-in expects exactly 3 arguments: value, path and new value");
+      }
+
+      const [value, path, newValue] = args;
+      if (!Array.isArray(path) || path.some(step => typeof step !== 'string' && typeof step !== 'number')) {
+        throw new Error("set-in expects second argument to be a list of keys and indexes");
+      }
+
+      return setIn(value, path, newValue);
+    });
+
     // Concat function for string concatenation (EAGER)
-    defineBuiltin("concat", true, (args: any[]) => {
+    defineBuiltin("concat", true, (args: any[], env: Environment) => {
       if (args.length === 0) {
         return "";
       }
       
+      if (isStrict(env)) {
+        const invalid = args.findIndex(arg => typeof arg !== 'string' && typeof arg !== 'number');
+        if (invalid !== -1) {
+          throw new Error(`concat expects strings or numbers in strict mode, got: ${typeName(args[invalid])}`);
+        }
+      }
+      
       // Convert all arguments to strings and concatenate
       return args.map(arg => {
         if (arg === null || arg === undefined) {
@@ -710,27 +1149,9 @@ export class RelayInterpreter {
       const components: RenderableComponent[] = [];
       
       for (let i = 0; i < list.length; i++) {
-        const item = list[i];
-        
-        // Create a new environment for function execution
-        const funcEnv: Environment = {
-          parent: func.closure || env,
-          bindings: new Map()
-        };
-        
-        // Set the parameter values in the function environment
-        if (func.params && func.params.length > 0) {
-          funcEnv.bindings.set(func.params[0], item);
-          
-          // If there's a second parameter, pass the index
-          if (func.params.length > 1) {
-            funcEnv.bindings.set(func.params[1], i);
-          }
-        }
-        
         try {
-          // Evaluate the function body to get the component
-          const component = evaluate(func.body, funcEnv);
+          // Call the function with the item and its index to get the component
+          const component = applyFunction(func, [list[i], i], evaluate);
           
           // If the result is a component, add it to our collection
           if (component && typeof component === 'object' && component.type === 'component') {
@@ -837,11 +1258,12 @@ export class RelayInterpreter {
         throw new Error("state expects first argument to be a variable name identifier");
       }
       const varName = (varNameArg as IdentifierNode).name;
+      expectNotConstant(varName, env);
       
       // Check if variable already exists, if so return its current value
       if (env.bindings.has(varName)) {
         const currentValue = env.bindings.get(varName);
-        console.log(`[STATE] Variable ${varName} already exists, current value: `, currentValue);
+        console.log(`[STATE] Variable ${varName} already exists, current value: `, loggableValue(currentValue, env, varName));
         return currentValue;
       }
       
@@ -851,7 +1273,7 @@ export class RelayInterpreter {
       // Set the variable in the environment
       env.bindings.set(varName, initialValue);
       
-      console.log(`[STATE] Initialized state variable: ${varName} = `, initialValue);
+      console.log(`[STATE] Initialized state variable: ${varName} = `, loggableValue(initialValue, env, varName));
       return initialValue;
     });
 
@@ -867,6 +1289,7 @@ export class RelayInterpreter {
         throw new Error("set expects first argument to be a variable name identifier");
       }
       const varName = (varNameArg as IdentifierNode).name;
+      expectNotConstant(varName, env);
       
       // Evaluate the new value
       const newValue = evaluate(args[1], env);
@@ -874,10 +1297,37 @@ export class RelayInterpreter {
       // Update the variable in the environment
       env.bindings.set(varName, newValue);
       
-      console.log(`[STATE] Updated state variable: ${varName} = `, newValue);
+      console.log(`[STATE] Updated state variable: ${varName} = `, loggableValue(newValue, env, varName));
       return newValue;
     });
 
+    // Const function for bindings that cannot be reassigned: const name value (LAZY - first argument is a name)
+    defineBuiltin("const", false, (args: ExpressionNode[], env: Environment, evaluate) => {
+      if (args.length !== 2) {
+        throw new Error("const expects exactly 2 arguments: name and value");
+      }
+
+      const nameArg = args[0];
+      if (nameArg.type !== 'identifier') {
+        throw new Error("const expects first argument to be an identifier");
+      }
+      const name = (nameArg as IdentifierNode).name;
+      expectNotConstant(name, env);
+
+      if (env.bindings.has(name)) {
+        throw new Error(`Cannot declare constant ${name}: it is already defined`);
+      }
+
+      const value = evaluate(args[1], env);
+
+      if (!env.bindings.has('_constants')) {
+        env.bindings.set('_constants', new Set<string>());
+      }
+      env.bindings.get('_constants').add(name);
+      setVariable(name, value, env);
+      return value;
+    });
+
     // List function for creating lists (EAGER)
     defineBuiltin("list", true, (args: any[]) => {
       // If only one argument and it's an array, return it directly
@@ -904,6 +1354,378 @@ export class RelayInterpreter {
       // Return a new array with the items added
       return [...list, ...itemsToAdd];
     });
+
+    // Round function for rounding numbers to decimal places (EAGER)
+    defineBuiltin("round", true, (args: any[]) => {
+      if (args.length < 1 || args.length > 2) {
+        throw new Error("round expects 1 or 2 arguments: number and optional decimal places");
+      }
+
+      const [value, places = 0] = args;
+      if (typeof value !== 'number') {
+        throw new Error("round expects first argument to be a number, got: " + typeof value);
+      }
+      if (!Number.isInteger(places) || places < 0 || places > 20) {
+        throw new Error("round expects decimal places to be an integer between 0 and 20");
+      }
+
+      return roundDecimal(value, places);
+    });
+
+    // Format-decimal function for fixed decimal places, e.g. prices (EAGER)
+    defineBuiltin("format-decimal", true, (args: any[]) => {
+      if (args.length !== 2) {
+        throw new Error("format-decimal expects exactly 2 arguments: number and decimal places");
+      }
+
+      const [value, places] = args;
+      if (typeof value !== 'number') {
+        throw new Error("format-decimal expects first argument to be a number, got: " + typeof value);
+      }
+      if (!Number.isInteger(places) || places < 0 || places > 20) {
+        throw new Error("format-decimal expects decimal places to be an integer between 0 and 20");
+      }
+
+      return roundDecimal(value, places).toFixed(places);
+    });
+
+    // Length function for strings (in Unicode code points) and lists (EAGER)
+    defineBuiltin("length", true, (args: any[]) => {
+      if (args.length !== 1) {
+        throw new Error("length expects exactly 1 argument: string or list");
+      }
+
+      const value = args[0];
+      if (typeof value === 'string') {
+        // Spreading iterates code points, so "🌱" has length 1 rather than 2
+        return [...value].length;
+      }
+      if (Array.isArray(value)) {
+        return value.length;
+      }
+
+      throw new Error("length expects a string or list, got: " + typeof value);
+    });
+
+    // Chars function for splitting strings into Unicode code points (EAGER)
+    defineBuiltin("chars", true, (args: any[]) => {
+      if (args.length !== 1 || typeof args[0] !== 'string') {
+        throw new Error("chars expects exactly 1 argument: string");
+      }
+
+      return [...args[0]];
+    });
+
+    // Bytes function for the UTF-8 encoding of strings (EAGER)
+    defineBuiltin("bytes", true, (args: any[]) => {
+      if (args.length !== 1 || typeof args[0] !== 'string') {
+        throw new Error("bytes expects exactly 1 argument: string");
+      }
+
+      // Encode by hand: TextEncoder is not available in every embedding (e.g. jsdom)
+      const bytes: number[] = [];
+      for (const char of args[0] as string) {
+        const code = char.codePointAt(0)!;
+        if (code < 0x80) {
+          bytes.push(code);
+        } else if (code < 0x800) {
+          bytes.push(0xc0 | (code >> 6), 0x80 | (code & 0x3f));
+        } else if (code < 0x10000) {
+          bytes.push(0xe0 | (code >> 12), 0x80 | ((code >> 6) & 0x3f), 0x80 | (code & 0x3f));
+        } else {
+          bytes.push(0xf0 | (code >> 18), 0x80 | ((code >> 12) & 0x3f), 0x80 | ((code >> 6) & 0x3f), 0x80 | (code & 0x3f));
+        }
+      }
+      return bytes;
+    });
+
+    // Sort-by function for stable sorting of lists by a key function (LAZY)
+    defineBuiltin("sort-by", false, (args: ExpressionNode[], env: Environment, evaluate) => {
+      if (args.length < 2 || args.length > 3) {
+        throw new Error("sort-by expects 2 or 3 arguments: list, key function and optional locale");
+      }
+
+      const list = evaluate(args[0], env);
+      const keyFunction = evaluate(args[1], env);
+      const locale = args.length === 3 ? evaluate(args[2], env) : undefined;
+
+      if (!Array.isArray(list)) {
+        throw new Error("sort-by expects first argument to be a list/array, got: " + typeof list);
+      }
+
+      if (!isRelayFunction(keyFunction)) {
+        throw new Error("sort-by expects second argument to be a function, got: " + typeof keyFunction);
+      }
+
+      if (locale !== undefined && typeof locale !== 'string') {
+        throw new Error("sort-by expects third argument to be a locale string, got: " + typeof locale);
+      }
+
+      // A locale switches string keys to collation order, e.g. "é" next to "e" instead of after "z"
+      let collator: Intl.Collator | undefined;
+      if (locale !== undefined) {
+        try {
+          collator = new Intl.Collator(locale);
+        } catch {
+          throw new Error("sort-by expects third argument to be a valid locale, got: " + JSON.stringify(locale));
+        }
+      }
+
+      // Compute each key once; Array.prototype.sort is stable so equal keys keep list order
+      const keyed = list.map((item, index) => ({ item, key: applyFunction(keyFunction, [item, index], evaluate) }));
+      keyed.sort((a, b) => compareValues(a.key, b.key, collator));
+
+      return keyed.map(entry => entry.item);
+    });
+
+    // Hash function for stable structural hashes of values (EAGER)
+    defineBuiltin("hash", true, (args: any[]) => {
+      if (args.length !== 1) {
+        throw new Error("hash expects exactly 1 argument: value");
+      }
+
+      return hashValue(args[0]);
+    });
+
+    // Select function for projecting objects onto a subset of fields (EAGER)
+    defineBuiltin("select", true, (args: any[]) => {
+      if (args.length !== 2) {
+        throw new Error("select expects exactly 2 arguments: value and field list");
+      }
+
+      const [value, fields] = args;
+
+      const isSelection = (selection: any): boolean =>
+        Array.isArray(selection) && selection.every(entry =>
+          typeof entry === 'string' ||
+          (entry !== null && typeof entry === 'object' && !Array.isArray(entry) && Object.values(entry).every(isSelection))
+        );
+
+      if (!isSelection(fields)) {
+        throw new Error("select expects second argument to be a list of field names or {field: [nested fields]} objects");
+      }
+
+      if (value === null || typeof value !== 'object') {
+        throw new Error("select expects first argument to be an object or list, got: " + typeof value);
+      }
+
+      return projectValue(value, fields);
+    });
+
+    // Diff function for listing structural differences between two values (EAGER)
+    defineBuiltin("diff", true, (args: any[]) => {
+      if (args.length !== 2) {
+        throw new Error("diff expects exactly 2 arguments: before and after values");
+      }
+
+      return diffValues(args[0], args[1]);
+    });
+
+    // Remove-where function for dropping matching items from lists (LAZY)
+    defineBuiltin("remove-where", false, (args: ExpressionNode[], env: Environment, evaluate) => {
+      if (args.length !== 2) {
+        throw new Error("remove-where expects exactly 2 arguments: list and predicate function");
+      }
+
+      const list = evaluate(args[0], env);
+      const predicate = evaluate(args[1], env);
+
+      if (!Array.isArray(list)) {
+        throw new Error("remove-where expects first argument to be a list/array, got: " + typeof list);
+      }
+
+      if (!isRelayFunction(predicate)) {
+        throw new Error("remove-where expects second argument to be a function, got: " + typeof predicate);
+      }
+
+      // Return a new array without the matching items, the original list is left untouched
+      return list.filter((item, index) => {
+        return !isTruthy(applyFunction(predicate, [item, index], evaluate));
+      });
+    });
+
+    // Index-by function for keyed lookups into lists of objects (EAGER)
+    defineBuiltin("index-by", true, (args: any[]) => {
+      if (args.length !== 2) {
+        throw new Error("index-by expects exactly 2 arguments: list and key");
+      }
+
+      const [list, key] = args;
+
+      if (!Array.isArray(list)) {
+        throw new Error("index-by expects first argument to be a list/array, got: " + typeof list);
+      }
+
+      if (typeof key !== 'string') {
+        throw new Error("index-by expects second argument to be a string key, got: " + typeof key);
+      }
+
+      // Later items win when two items share a key, matching what a linear "find last" would return
+      const index: Record<string, any> = {};
+      for (const item of list) {
+        if (item && typeof item === 'object' && item[key] !== undefined && item[key] !== null) {
+          // defineProperty so a key such as "__proto__" becomes a field instead of the prototype
+          Object.defineProperty(index, String(item[key]), { value: item, enumerable: true, writable: true, configurable: true });
+        }
+      }
+
+      return index;
+    });
+
+    // Def-messages function for registering a locale's message catalog (EAGER)
+    defineBuiltin("def-messages", true, (args: any[], env: Environment) => {
+      if (args.length !== 2) {
+        throw new Error("def-messages expects exactly 2 arguments: locale and messages");
+      }
+
+      const [locale, messages] = args;
+
+      if (typeof locale !== 'string') {
+        throw new Error("def-messages expects first argument to be a locale string, got: " + typeof locale);
+      }
+
+      if (typeof messages !== 'object' || messages === null || Array.isArray(messages)) {
+        throw new Error("def-messages expects second argument to be an object of messages");
+      }
+
+      // Catalogs live in the environment, like event handlers, so each program gets its own
+      if (!env.bindings.has('_messages')) {
+        env.bindings.set('_messages', new Map());
+      }
+      const catalogs: Map<string, Record<string, any>> = env.bindings.get('_messages');
+      catalogs.set(locale, { ...(catalogs.get(locale) || {}), ...messages });
+
+      return locale;
+    });
+
+    // Set-locale function for choosing the active locale (EAGER)
+    defineBuiltin("set-locale", true, (args: any[], env: Environment) => {
+      if (args.length !== 1 || typeof args[0] !== 'string') {
+        throw new Error("set-locale expects exactly 1 argument: locale string");
+      }
+
+      env.bindings.set('_locale', args[0]);
+      return args[0];
+    });
+
+    // Translate function for looking up and interpolating messages (EAGER)
+    defineBuiltin("translate", true, (args: any[], env: Environment) => {
+      if (args.length < 1 || args.length > 2) {
+        throw new Error("translate expects 1 or 2 arguments: key and optional params");
+      }
+
+      const [key, params] = args;
+
+      if (typeof key !== 'string') {
+        throw new Error("translate expects first argument to be a message key string, got: " + typeof key);
+      }
+
+      const catalogs: Map<string, Record<string, any>> = findBinding('_messages', env) || new Map();
+      const locale: string = findBinding('_locale', env) || 'en';
+
+      // Try the exact locale, then its language ("pt-BR" -> "pt"), then English
+      const candidates = [locale, locale.split('-')[0], 'en'];
+      let message: any = key;
+      for (const candidate of candidates) {
+        const catalog = catalogs.get(candidate);
+        if (catalog && typeof catalog[key] === 'string') {
+          message = catalog[key];
+          break;
+        }
+      }
+
+      if (!params || typeof params !== 'object') {
+        return message;
+      }
+
+      return message.replace(/\{(\w+)\}/g, (placeholder: string, name: string) =>
+        !hasOwnField(params, name) || params[name] === undefined || params[name] === null ? placeholder : String(params[name])
+      );
+    });
+
+    // Redact function for masking variables and object fields in logs (EAGER)
+    defineBuiltin("redact", true, (args: any[], env: Environment) => {
+      if (args.length === 0 || args.some(arg => typeof arg !== 'string')) {
+        throw new Error("redact expects at least 1 argument: field or variable names as strings");
+      }
+
+      if (!env.bindings.has('_redactedFields')) {
+        env.bindings.set('_redactedFields', new Set<string>());
+      }
+      const fields: Set<string> = env.bindings.get('_redactedFields');
+      args.forEach(name => fields.add(name));
+
+      return args;
+    });
+
+    // Search function for full-text queries over lists of objects (EAGER)
+    defineBuiltin("search", true, (args: any[]) => {
+      if (args.length < 2 || args.length > 3) {
+        throw new Error("search expects 2 or 3 arguments: list, query and optional fields");
+      }
+
+      const [list, query, fields] = args;
+
+      if (!Array.isArray(list)) {
+        throw new Error("search expects first argument to be a list/array, got: " + typeof list);
+      }
+
+      if (typeof query !== 'string') {
+        throw new Error("search expects second argument to be a query string, got: " + typeof query);
+      }
+
+      if (fields !== undefined && (!Array.isArray(fields) || fields.some(field => typeof field !== 'string'))) {
+        throw new Error("search expects third argument to be a list of field names");
+      }
+
+      // Build an inverted index of term -> (item position -> occurrences)
+      const index = new Map<string, Map<number, number>>();
+      list.forEach((item, position) => {
+        let texts: any[];
+        if (typeof item === 'string') {
+          texts = [item];
+        } else if (item && typeof item === 'object') {
+          texts = fields ? fields.map((field: string) => item[field]) : Object.values(item);
+        } else {
+          texts = [];
+        }
+
+        for (const text of texts) {
+          if (typeof text !== 'string') continue;
+          for (const term of searchTerms(text)) {
+            const postings = index.get(term) || new Map<number, number>();
+            postings.set(position, (postings.get(position) || 0) + 1);
+            index.set(term, postings);
+          }
+        }
+      });
+
+      const terms = searchTerms(query);
+      if (terms.length === 0) {
+        return [];
+      }
+
+      // Every query term must match; score by total occurrences
+      const scores = new Map<number, number>();
+      for (const [position, count] of index.get(terms[0]) || []) {
+        scores.set(position, count);
+      }
+      for (const term of terms.slice(1)) {
+        const postings = index.get(term) || new Map<number, number>();
+        for (const [position, score] of scores) {
+          if (postings.has(position)) {
+            scores.set(position, score + postings.get(position)!);
+          } else {
+            scores.delete(position);
+          }
+        }
+      }
+
+      // Highest score first, ties keep list order
+      return [...scores.entries()]
+        .sort((a, b) => b[1] - a[1] || a[0] - b[0])
+        .map(([position]) => list[position]);
+    });
   }
 }
 
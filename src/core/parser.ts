@@ -92,13 +92,23 @@ export class RelayLexer {
   private column: number = 1;
   private indentStack: number[] = [0];
   private tokens: Token[] = [];
+  private timeoutMs?: number;
+  private deadline: number;
+  private steps: number = 0;
 
-  constructor(source: string) {
+  constructor(source: string, options: ParseOptions = {}, startedAt: number = Date.now()) {
     this.source = source;
+    this.timeoutMs = options.timeoutMs;
+    this.deadline = options.timeoutMs !== undefined ? startedAt + options.timeoutMs : Infinity;
   }
 
   tokenize(): Token[] {
     while (this.pos < this.source.length) {
+      // Reading the clock for every token is measurable on large inputs, so sample it
+      if (this.steps++ % 256 === 0 && this.timeoutMs !== undefined && Date.now() > this.deadline) {
+        throw new Error(`Parsing exceeded the time limit of ${this.timeoutMs}ms at line ${this.line}`);
+      }
+      
       this.skipWhitespace();
       
       if (this.pos >= this.source.length) break;
@@ -259,36 +269,80 @@ export class RelayLexer {
   }
 
   private handleString(): void {
-    const start = this.pos;
     this.advance(); // Skip opening quote
     
-    let value = '';
+    // Copy runs of plain characters with a single slice instead of one concatenation
+    // per character; most strings have no escapes and become exactly one slice
+    const parts: string[] = [];
+    let chunkStart = this.pos;
     
     while (this.pos < this.source.length && this.source[this.pos] !== '"') {
       if (this.source[this.pos] === '\\' && this.pos + 1 < this.source.length) {
-        // Handle escape sequences
-        this.advance();
-        const escaped = this.source[this.pos];
-        switch (escaped) {
-          case 'n': value += '\n'; break;
-          case 't': value += '\t'; break;
-          case 'r': value += '\r'; break;
-          case '\\': value += '\\'; break;
-          case '"': value += '"'; break;
-          default: value += escaped; break;
-        }
+        parts.push(this.source.slice(chunkStart, this.pos));
+        this.advance(); // Skip backslash
+        parts.push(this.readEscape());
+        chunkStart = this.pos;
       } else {
-        value += this.source[this.pos];
+        this.advance();
       }
-      this.advance();
     }
     
     if (this.pos >= this.source.length) {
       throw new Error(`Unterminated string at line ${this.line}`);
     }
     
+    parts.push(this.source.slice(chunkStart, this.pos));
     this.advance(); // Skip closing quote
-    this.addToken('STRING', value);
+    this.addToken('STRING', parts.length === 1 ? parts[0] : parts.join(''));
+  }
+
+  // Decode the escape sequence after a backslash and move past it
+  private readEscape(): string {
+    const escaped = this.source[this.pos];
+    this.advance();
+    
+    switch (escaped) {
+      case 'n': return '\n';
+      case 't': return '\t';
+      case 'r': return '\r';
+      case 'b': return '\b';
+      case 'f': return '\f';
+      case '\\': return '\\';
+      case '"': return '"';
+      case 'u': return this.readUnicodeEscape();
+      default: return escaped; // Unknown escapes keep the character, e.g. \q is q
+    }
+  }
+
+  // \u{1F600} with 1-6 hex digits, or \u00E9 with exactly 4
+  private readUnicodeEscape(): string {
+    const start = this.pos;
+    let hex: string;
+    
+    if (this.source[this.pos] === '{') {
+      const close = this.source.indexOf('}', this.pos);
+      hex = close === -1 ? '' : this.source.slice(this.pos + 1, close);
+      if (!/^[0-9a-fA-F]{1,6}$/.test(hex)) {
+        throw new Error(`Invalid unicode escape \\u${this.source.slice(start, close === -1 ? this.pos + 1 : close + 1)} at line ${this.line}`);
+      }
+      while (this.pos <= close) {
+        this.advance();
+      }
+    } else {
+      hex = this.source.slice(this.pos, this.pos + 4);
+      if (!/^[0-9a-fA-F]{4}$/.test(hex)) {
+        throw new Error(`Invalid unicode escape \\u${hex} at line ${this.line}: expected 4 hex digits or \\u{...}`);
+      }
+      for (let i = 0; i < 4; i++) {
+        this.advance();
+      }
+    }
+    
+    const codePoint = parseInt(hex, 16);
+    if (codePoint > 0x10ffff) {
+      throw new Error(`Invalid unicode escape \\u{${hex}} at line ${this.line}: code point out of range`);
+    }
+    return String.fromCodePoint(codePoint);
   }
 
   private handleNumber(): void {
@@ -299,25 +353,64 @@ export class RelayLexer {
       this.advance();
     }
     
-    // Handle integer part
-    while (this.pos < this.source.length && this.isDigit(this.source[this.pos])) {
-      this.advance();
+    // Handle hexadecimal literals: 0xFF, 0xdead_beef
+    if (this.source[this.pos] === '0' && (this.peek() === 'x' || this.peek() === 'X') && this.isHexDigit(this.source[this.pos + 2] || '')) {
+      this.advance(); // Skip 0
+      this.advance(); // Skip x
+      this.readDigits(char => this.isHexDigit(char), start);
+      
+      const text = this.source.slice(start, this.pos).replace(/_/g, '');
+      const negative = text.startsWith('-');
+      const value = parseInt(text.slice(negative ? 3 : 2), 16);
+      
+      this.addToken('NUMBER', negative ? -value : value);
+      return;
     }
     
+    // Handle integer part
+    this.readDigits(char => this.isDigit(char), start);
+    
     // Handle decimal part
     if (this.pos < this.source.length && this.source[this.pos] === '.') {
       this.advance();
-      while (this.pos < this.source.length && this.isDigit(this.source[this.pos])) {
+      this.readDigits(char => this.isDigit(char), start);
+    }
+    
+    // Handle exponent part, only when digits follow so "2em" stays a number and an identifier
+    const exponentSign = this.peek() === '+' || this.peek() === '-';
+    const exponentDigit = exponentSign ? this.source[this.pos + 2] || '' : this.peek();
+    if ((this.source[this.pos] === 'e' || this.source[this.pos] === 'E') && this.isDigit(exponentDigit)) {
+      this.advance(); // Skip e
+      if (exponentSign) {
         this.advance();
       }
+      this.readDigits(char => this.isDigit(char), start);
     }
     
-    const text = this.source.slice(start, this.pos);
-    const value = text.includes('.') ? parseFloat(text) : parseInt(text, 10);
+    const text = this.source.slice(start, this.pos).replace(/_/g, '');
+    const value = /[.eE]/.test(text) ? parseFloat(text) : parseInt(text, 10);
     
     this.addToken('NUMBER', value);
   }
 
+  // Read a run of digits where single underscores may separate digits (1_000_000)
+  private readDigits(isValidDigit: (char: string) => boolean, literalStart: number): void {
+    while (this.pos < this.source.length) {
+      const char = this.source[this.pos];
+      if (isValidDigit(char)) {
+        this.advance();
+      } else if (char === '_' && isValidDigit(this.source[this.pos - 1]) && isValidDigit(this.peek())) {
+        this.advance();
+      } else {
+        break;
+      }
+    }
+    
+    if (this.source[this.pos] === '_') {
+      throw new Error(`Invalid numeric literal ${this.source.slice(literalStart, this.pos + 1)} at line ${this.line}: underscores must separate digits`);
+    }
+  }
+
   private handleIdentifier(): void {
     const start = this.pos;
     const startColumn = this.column;
@@ -369,10 +462,15 @@ export class RelayLexer {
     return char >= '0' && char <= '9';
   }
 
+  private isHexDigit(char: string): boolean {
+    return this.isDigit(char) || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F');
+  }
+
   private isIdentifierStart(char: string): boolean {
     return (char >= 'a' && char <= 'z') || 
            (char >= 'A' && char <= 'Z') || 
-           char === '_';
+           char === '_' ||
+           (char > '\x7f' && /\p{L}/u.test(char)); // Non-ASCII letters, e.g. café or título
   }
 
   private isIdentifierPart(char: string): boolean {
@@ -407,12 +505,30 @@ export class RelayLexer {
   }
 }
 
+// Deepest expression nesting the parser accepts before reporting an error
+// instead of overflowing the JavaScript call stack
+export const MAX_NESTING_DEPTH = 500;
+
+// Limits for parsing untrusted programs; unset limits are not enforced
+export interface ParseOptions {
+  maxSourceLength?: number; // characters of source text
+  maxNodes?: number; // expressions and arguments parsed, a rough measure of AST size
+  timeoutMs?: number; // wall time spent tokenizing and building the AST
+  maxDepth?: number; // defaults to MAX_NESTING_DEPTH
+}
+
 export class RelayParser {
   private tokens: Token[];
   private pos: number = 0;
+  private depth: number = 0;
+  private nodeCount: number = 0;
+  private options: ParseOptions;
+  private deadline: number;
 
-  constructor(tokens: Token[]) {
+  constructor(tokens: Token[], options: ParseOptions = {}, startedAt: number = Date.now()) {
     this.tokens = tokens;
+    this.options = options;
+    this.deadline = options.timeoutMs !== undefined ? startedAt + options.timeoutMs : Infinity;
   }
 
   // program = expression*
@@ -437,6 +553,15 @@ export class RelayParser {
 
   // expression = funcall | atom | lambda | sequence | comment
   parseExpression(): ExpressionNode {
+    this.enterExpression();
+    try {
+      return this.parseNestedExpression();
+    } finally {
+      this.depth--;
+    }
+  }
+
+  private parseNestedExpression(): ExpressionNode {
     // Handle comments
     if (this.check('COMMENT')) {
       const comment = this.advance();
@@ -474,16 +599,16 @@ export class RelayParser {
       return expr;
     }
     
+    // Check for infix operator pattern: identifier operator ... or number operator ...
+    if (this.isInfixExpression()) {
+      return this.parseInfixExpression();
+    }
+    
     // Handle atoms that aren't function calls
     if (this.isAtomOnly()) {
       return this.parseAtom();
     }
     
-    // Check for infix operator pattern: identifier operator ...
-    if (this.isInfixExpression()) {
-      return this.parseInfixExpression();
-    }
-    
     // Default to function call for identifiers and operators
     // In Relay, all identifiers are function calls (no bare identifier references)
     return this.parseFuncall();
@@ -492,7 +617,12 @@ export class RelayParser {
   // funcall = identifier argument_list
   parseFuncall(): FuncallNode {
     const name = this.parseIdentifier();
-    const args = this.parseArgumentList();
+    let args = this.parseArgumentList();
+    
+    if (name === 'if') {
+      // Single-line form: if cond then a else b is the same call as if cond a b
+      args = this.inlineIfArgs(args) ?? args;
+    }
     
     return {
       type: 'funcall',
@@ -501,6 +631,28 @@ export class RelayParser {
     };
   }
 
+  // if_inline = "if" (expression | operand operator operand) "then" expression "else" expression
+  private inlineIfArgs(args: ExpressionNode[]): ExpressionNode[] | null {
+    const isKeyword = (node: ExpressionNode, keyword: string) =>
+      node.type === 'identifier' && (node as IdentifierNode).name === keyword;
+    const thenIndex = args.length - 4;
+    if (thenIndex < 1 || !isKeyword(args[thenIndex], 'then') || !isKeyword(args[thenIndex + 2], 'else')) {
+      return null;
+    }
+
+    if (thenIndex === 1) {
+      return [args[0], args[2], args[4]];
+    }
+    // Inside an argument list an operator arrives as an identifier, so x > 0 is rebuilt
+    // here as (> x 0). Longer conditions still need parentheses
+    const operator = args[1].type === 'identifier' ? (args[1] as IdentifierNode).name : '';
+    if (thenIndex === 3 && operator.length === 1 && '+-*/%<>=!'.includes(operator)) {
+      const condition: FuncallNode = { type: 'funcall', name: operator, args: [args[0], args[2]] };
+      return [condition, args[4], args[6]];
+    }
+    return null;
+  }
+
   // argument_list = mixed inline and parenthesized args | sequence_arg | parenthesized_args
   parseArgumentList(): ExpressionNode[] {
     // Check for sequence argument ONLY (newline + indent)
@@ -523,6 +675,8 @@ export class RelayParser {
           continue;
         }
         
+        this.countNode();
+        
         // Parse arguments inline - same logic as mixed inline arguments
         if (this.check('LPAREN')) {
           // Parenthesized expression - parse as function call
@@ -568,6 +722,8 @@ export class RelayParser {
     
     // Parse mixed inline arguments (including parenthesized expressions)
     while (this.hasMoreInlineArgs()) {
+      this.countNode();
+      
       if (this.check('LPAREN')) {
         // Parenthesized expression - parse as function call
         this.advance(); // consume '('
@@ -834,6 +990,35 @@ export class RelayParser {
   }
 
   // Helper methods
+  private enterExpression(): void {
+    const maxDepth = this.options.maxDepth ?? MAX_NESTING_DEPTH;
+    const line = this.currentToken()?.line || 'EOF';
+    
+    if (this.depth + 1 > maxDepth) {
+      throw new Error(`Expression nesting exceeds ${maxDepth} levels at line ${line}`);
+    }
+    
+    this.countNode();
+    this.depth++;
+  }
+
+  // Count a parsed node against maxNodes and check the time limit. Called for every
+  // expression and every inline argument, so long flat argument lists are bounded too
+  private countNode(): void {
+    const line = this.currentToken()?.line || 'EOF';
+    
+    if (this.options.maxNodes !== undefined && this.nodeCount + 1 > this.options.maxNodes) {
+      throw new Error(`Program exceeds the limit of ${this.options.maxNodes} expressions at line ${line}`);
+    }
+    
+    // Sampled like the lexer, and skipped entirely when no time limit is set
+    if (this.nodeCount % 256 === 0 && this.options.timeoutMs !== undefined && Date.now() > this.deadline) {
+      throw new Error(`Parsing exceeded the time limit of ${this.options.timeoutMs}ms at line ${line}`);
+    }
+    
+    this.nodeCount++;
+  }
+
   parseIdentifier(): string {
     if (!this.check('IDENTIFIER') && !this.check('OPERATOR')) {
       throw new Error(`Expected identifier at line ${this.currentToken()?.line}`);
@@ -963,19 +1148,32 @@ export class RelayParser {
 
   isInfixExpression(): boolean {
     // Check for pattern: identifier operator ...
-    if (this.check('IDENTIFIER') && 
-        this.pos + 1 < this.tokens.length && 
-        this.tokens[this.pos + 1].type === 'OPERATOR') {
+    if (this.pos + 1 >= this.tokens.length || this.tokens[this.pos + 1].type !== 'OPERATOR') {
+      return false;
+    }
+    if (this.check('IDENTIFIER')) {
       return true;
     }
-    return false;
+    // A number on the left only starts a chainable comparison, as in 0 < x < 10. Infix
+    // expressions group from the right, so 10 - 3 - 2 would silently mean 10 - (3 - 2)
+    const operator = this.tokens[this.pos + 1].value;
+    return this.check('NUMBER') && (operator === '<' || operator === '>');
   }
 
   parseInfixExpression(): FuncallNode {
+    this.enterExpression();
+    try {
+      return this.parseInfixOperands();
+    } finally {
+      this.depth--;
+    }
+  }
+
+  private parseInfixOperands(): FuncallNode {
     // Parse infix expression: identifier operator ... 
     // Convert to prefix: operator identifier ...
     
-    const leftOperand = this.parseIdentifierRef(); // consume identifier
+    const leftOperand = this.check('NUMBER') ? this.parseAtom() : this.parseIdentifierRef(); // consume left operand
     const operator = this.advance(); // consume operator
     
     // Parse the rest as arguments
@@ -983,6 +1181,8 @@ export class RelayParser {
     
     // Parse remaining arguments
     while (this.hasMoreInlineArgs()) {
+      this.countNode();
+      
       if (this.check('LPAREN')) {
         // Parenthesized expression
         this.advance(); // consume '('
@@ -998,15 +1198,14 @@ export class RelayParser {
       } else if (this.isLambdaStart()) {
         // Lambda expression
         args.push(this.parseLambda());
-      } else if (this.check('IDENTIFIER') && this.hasMoreInlineArgs()) {
-        // Check if this is another infix expression or just an atom
-        const nextPos = this.pos + 1;
-        if (nextPos < this.tokens.length && this.tokens[nextPos].type === 'OPERATOR') {
-          // This is another infix expression - parse it recursively
-          args.push(this.parseInfixExpression());
+      } else if (this.isInfixExpression()) {
+        // This is another infix expression - parse it recursively
+        const nested = this.parseInfixExpression();
+        if ((operator.value === '<' || operator.value === '>') && nested.name === operator.value && args.length === 1) {
+          // Chained comparison: a < b < c becomes (< a b c) rather than a < (b < c)
+          args.push(...nested.args);
         } else {
-          // Just an identifier reference
-          args.push(this.parseIdentifierRef());
+          args.push(nested);
         }
       } else if (this.isIdentifierRef()) {
         // Identifier reference
@@ -1062,14 +1261,20 @@ export class RelayParser {
 }
 
 // Usage functions
-export function tokenize(source: string): Token[] {
-  const lexer = new RelayLexer(source);
+export function tokenize(source: string, options: ParseOptions = {}): Token[] {
+  const lexer = new RelayLexer(source, options);
   return lexer.tokenize();
 }
 
-export function parse(source: string): ProgramNode {
-  const tokens = tokenize(source);
-  const parser = new RelayParser(tokens);
+export function parse(source: string, options: ParseOptions = {}): ProgramNode {
+  if (options.maxSourceLength !== undefined && source.length > options.maxSourceLength) {
+    throw new Error(`Program is ${source.length} characters, larger than the limit of ${options.maxSourceLength}`);
+  }
+  
+  // Tokenizing and parsing share one time budget
+  const startedAt = Date.now();
+  const tokens = new RelayLexer(source, options, startedAt).tokenize();
+  const parser = new RelayParser(tokens, options, startedAt);
   return parser.parseProgram();
 }
 
@@ -0,0 +1,12 @@
+// Relay Test Helpers
+// Shared by the fuzz and property test suites; kept outside __tests__ so Jest does not
+// collect this file as a suite of its own
+
+// Small seeded PRNG (a linear congruential generator) so failures can be replayed from the seed
+export function createRandom(seed: number): () => number {
+  let state = seed;
+  return () => {
+    state = (state * 1103515245 + 12345) & 0x7fffffff;
+    return state / 0x7fffffff;
+  };
+}
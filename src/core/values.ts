@@ -0,0 +1,376 @@
+// Relay Value Utilities
+// Generic helpers over runtime values: walking, copying, freezing, hashing, redaction,
+// projection, diffing and nested path access
+
+import { RelayFunction } from './interpreter';
+
+// Location of a value inside its root, e.g. ["posts", 0, "title"]
+export type ValuePath = (string | number)[];
+
+// Visitor called for every value; return false to skip the value's children
+export type ValueVisitor = (value: any, path: ValuePath) => void | boolean;
+
+function isRelayFunctionValue(value: any): boolean {
+  return value !== null && typeof value === 'object' && (value as RelayFunction).type === 'function';
+}
+
+// Functions carry their closure environment and are shared rather than traversed
+function isContainer(value: any): boolean {
+  return value !== null && typeof value === 'object' && !isRelayFunctionValue(value);
+}
+
+// Check for a field or list index the value holds itself, ignoring inherited members
+// like toString and the length of lists
+export function hasOwnField(value: any, key: string | number): boolean {
+  if (Array.isArray(value)) {
+    return typeof key === 'number' && Number.isInteger(key) && key >= 0 && key < value.length;
+  }
+  return isContainer(value) && Object.prototype.hasOwnProperty.call(value, key);
+}
+
+// Visit a value and everything nested in it, depth first, parents before children
+export function walkValue(value: any, visitor: ValueVisitor, path: ValuePath = []): void {
+  if (visitor(value, path) === false || !isContainer(value)) {
+    return;
+  }
+
+  if (Array.isArray(value)) {
+    value.forEach((item, index) => walkValue(item, visitor, [...path, index]));
+  } else {
+    for (const key of Object.keys(value)) {
+      walkValue(value[key], visitor, [...path, key]);
+    }
+  }
+}
+
+// Copy lists and objects all the way down so the copy shares no mutable state
+export function deepCopy<T>(value: T): T {
+  if (!isContainer(value)) {
+    return value;
+  }
+
+  if (Array.isArray(value)) {
+    return value.map(item => deepCopy(item)) as unknown as T;
+  }
+
+  const copy: Record<string, any> = {};
+  for (const key of Object.keys(value as Record<string, any>)) {
+    copy[key] = deepCopy((value as Record<string, any>)[key]);
+  }
+  return copy as T;
+}
+
+// Recursively freeze lists and objects; writes then throw in strict mode code
+export function freezeValue<T>(value: T): T {
+  walkValue(value, item => {
+    if (isContainer(item)) {
+      Object.freeze(item);
+    }
+  });
+  return value;
+}
+
+// Check whether a value and everything nested in it is frozen
+export function isFrozenValue(value: any): boolean {
+  let frozen = true;
+  walkValue(value, item => {
+    if (isContainer(item) && !Object.isFrozen(item)) {
+      frozen = false;
+    }
+    return frozen;
+  });
+  return frozen;
+}
+
+// Serialize a value with object keys sorted, so equal values always give the same text
+// regardless of the order their fields were inserted in
+export function canonicalString(value: any): string {
+  if (value === null || value === undefined) {
+    return 'null';
+  }
+
+  switch (typeof value) {
+    case 'boolean':
+      return String(value);
+    case 'number':
+      // JSON would turn NaN and Infinity into null; -0 and 0 are the same value in Relay
+      return Number.isFinite(value) ? JSON.stringify(value === 0 ? 0 : value) : String(value);
+    case 'string':
+      return JSON.stringify(value);
+  }
+
+  if (!isContainer(value)) {
+    throw new Error(`Cannot canonicalize ${isRelayFunctionValue(value) ? 'functions' : typeof value}`);
+  }
+
+  if (Array.isArray(value)) {
+    return '[' + value.map(item => canonicalString(item)).join(',') + ']';
+  }
+
+  const keys = Object.keys(value).filter(key => value[key] !== undefined).sort();
+  return '{' + keys.map(key => JSON.stringify(key) + ':' + canonicalString(value[key])).join(',') + '}';
+}
+
+// Stable structural hash of a value as 14 hex digits (53 bits, cyrb53), suitable for
+// cache keys and dedupe; not a cryptographic hash
+export function hashValue(value: any): string {
+  const text = canonicalString(value);
+  let h1 = 0xdeadbeef;
+  let h2 = 0x41c6ce57;
+
+  for (let i = 0; i < text.length; i++) {
+    const code = text.charCodeAt(i);
+    h1 = Math.imul(h1 ^ code, 2654435761);
+    h2 = Math.imul(h2 ^ code, 1597334677);
+  }
+
+  h1 = Math.imul(h1 ^ (h1 >>> 16), 2246822507) ^ Math.imul(h2 ^ (h2 >>> 13), 3266489909);
+  h2 = Math.imul(h2 ^ (h2 >>> 16), 2246822507) ^ Math.imul(h1 ^ (h1 >>> 13), 3266489909);
+
+  const hash = 4294967296 * (2097151 & h2) + (h1 >>> 0);
+  return hash.toString(16).padStart(14, '0');
+}
+
+// Placeholder logged instead of redacted values
+export const REDACTED = '[REDACTED]';
+
+// Copy a value with the values of the named object fields masked, for logging
+export function redactValue(value: any, fields: Set<string>): any {
+  if (fields.size === 0 || !isContainer(value)) {
+    return value;
+  }
+
+  if (Array.isArray(value)) {
+    return value.map(item => redactValue(item, fields));
+  }
+
+  const copy: Record<string, any> = {};
+  for (const key of Object.keys(value)) {
+    copy[key] = fields.has(key) ? REDACTED : redactValue(value[key], fields);
+  }
+  return copy;
+}
+
+// Field selection for projectValue: plain names, or { field: nestedSelection } for sub-objects
+export type FieldSelection = (string | Record<string, FieldSelection>)[];
+
+// Keep only the selected fields of an object (or of every object in a list).
+// Missing fields are left out rather than set to null
+export function projectValue(value: any, selection: FieldSelection): any {
+  if (Array.isArray(value)) {
+    return value.map(item => projectValue(item, selection));
+  }
+
+  if (!isContainer(value)) {
+    return value;
+  }
+
+  const result: Record<string, any> = {};
+  for (const entry of selection) {
+    if (typeof entry === 'string') {
+      if (hasOwnField(value, entry)) {
+        result[entry] = value[entry];
+      }
+      continue;
+    }
+
+    for (const key of Object.keys(entry)) {
+      if (hasOwnField(value, key)) {
+        result[key] = projectValue(value[key], entry[key]);
+      }
+    }
+  }
+  return result;
+}
+
+// One difference between two values; paths point into the new value for added and
+// changed entries and into the old value for removed ones
+export interface ValueChange {
+  kind: 'added' | 'removed' | 'changed';
+  path: ValuePath;
+  before?: any;
+  after?: any;
+}
+
+// Structural equality: lists and objects match when their contents do, regardless of
+// field order; functions only match themselves
+export function sameValue(a: any, b: any): boolean {
+  if (a === b || (a ?? null) === (b ?? null)) {
+    return true;
+  }
+  if (typeof a === 'number' && typeof b === 'number') {
+    return Number.isNaN(a) && Number.isNaN(b);
+  }
+  if (!isContainer(a) || !isContainer(b) || Array.isArray(a) !== Array.isArray(b)) {
+    return false;
+  }
+
+  if (Array.isArray(a)) {
+    return a.length === b.length && a.every((item, index) => sameValue(item, b[index]));
+  }
+
+  // Fields holding undefined count as missing, as in canonicalString
+  const keysA = Object.keys(a).filter(key => a[key] !== undefined);
+  const keysB = Object.keys(b).filter(key => b[key] !== undefined);
+  return keysA.length === keysB.length &&
+    keysA.every(key => Object.prototype.hasOwnProperty.call(b, key) && sameValue(a[key], b[key]));
+}
+
+// Structural difference between two values: missing and extra fields, changed values,
+// and list insertions/removals (matched with a longest common subsequence)
+export function diffValues(before: any, after: any, path: ValuePath = []): ValueChange[] {
+  if (sameValue(before, after)) {
+    return [];
+  }
+
+  if (Array.isArray(before) && Array.isArray(after)) {
+    return diffLists(before, after, path);
+  }
+
+  const isObject = (value: any) => isContainer(value) && !Array.isArray(value);
+  if (!isObject(before) || !isObject(after)) {
+    return [{ kind: 'changed', path, before, after }];
+  }
+
+  const changes: ValueChange[] = [];
+  for (const key of Object.keys(before)) {
+    if (!hasOwnField(after, key)) {
+      changes.push({ kind: 'removed', path: [...path, key], before: before[key] });
+    }
+  }
+  for (const key of Object.keys(after)) {
+    if (hasOwnField(before, key)) {
+      changes.push(...diffValues(before[key], after[key], [...path, key]));
+    } else {
+      changes.push({ kind: 'added', path: [...path, key], after: after[key] });
+    }
+  }
+  return changes;
+}
+
+// Canonical text of a value, or null when it holds functions and cannot be serialized
+function canonicalKey(value: any): string | null {
+  try {
+    return canonicalString(value);
+  } catch {
+    return null;
+  }
+}
+
+function diffLists(before: any[], after: any[], path: ValuePath): ValueChange[] {
+  // Serialize each item once instead of once per comparison in the n*m table below
+  const beforeKeys = before.map(canonicalKey);
+  const afterKeys = after.map(canonicalKey);
+  const same = (i: number, j: number) => beforeKeys[i] !== null && afterKeys[j] !== null
+    ? beforeKeys[i] === afterKeys[j]
+    : sameValue(before[i], after[j]);
+
+  // lengths[i][j] = length of the common subsequence of before[i..] and after[j..]
+  const lengths: number[][] = Array.from({ length: before.length + 1 }, () => new Array(after.length + 1).fill(0));
+  for (let i = before.length - 1; i >= 0; i--) {
+    for (let j = after.length - 1; j >= 0; j--) {
+      lengths[i][j] = same(i, j)
+        ? lengths[i + 1][j + 1] + 1
+        : Math.max(lengths[i + 1][j], lengths[i][j + 1]);
+    }
+  }
+
+  const changes: ValueChange[] = [];
+  let removed: number[] = [];
+  let added: number[] = [];
+
+  // Items dropped and inserted at the same spot are reported as changes to that item
+  const flush = () => {
+    const paired = Math.min(removed.length, added.length);
+    for (let k = 0; k < paired; k++) {
+      changes.push(...diffValues(before[removed[k]], after[added[k]], [...path, added[k]]));
+    }
+    removed.slice(paired).forEach(i => changes.push({ kind: 'removed', path: [...path, i], before: before[i] }));
+    added.slice(paired).forEach(j => changes.push({ kind: 'added', path: [...path, j], after: after[j] }));
+    removed = [];
+    added = [];
+  };
+
+  let i = 0;
+  let j = 0;
+  while (i < before.length || j < after.length) {
+    if (i < before.length && j < after.length && same(i, j)) {
+      flush();
+      i++;
+      j++;
+    } else if (j >= after.length || (i < before.length && lengths[i + 1][j] >= lengths[i][j + 1])) {
+      removed.push(i++);
+    } else {
+      added.push(j++);
+    }
+  }
+  flush();
+
+  return changes;
+}
+
+// Render a path like posts[0].title
+export function formatPath(path: ValuePath): string {
+  if (path.length === 0) {
+    return '(root)';
+  }
+  return path.map((part, index) => {
+    if (typeof part === 'number') return `[${part}]`;
+    return /^[A-Za-z_][\w-]*$/.test(part) ? (index === 0 ? part : `.${part}`) : `[${JSON.stringify(part)}]`;
+  }).join('');
+}
+
+// Render changes as one line each: "+ path: value", "- path: value", "~ path: old -> new"
+export function formatDiff(changes: ValueChange[]): string {
+  const show = (value: any) => isRelayFunctionValue(value) ? '<function>' : JSON.stringify(value === undefined ? null : value);
+  return changes.map(change => {
+    switch (change.kind) {
+      case 'added': return `+ ${formatPath(change.path)}: ${show(change.after)}`;
+      case 'removed': return `- ${formatPath(change.path)}: ${show(change.before)}`;
+      default: return `~ ${formatPath(change.path)}: ${show(change.before)} -> ${show(change.after)}`;
+    }
+  }).join('\n');
+}
+
+// Read a nested value; any missing step along the path yields null instead of an error
+export function getIn(value: any, path: ValuePath): any {
+  let current = value;
+  for (const step of path) {
+    if (!hasOwnField(current, step)) {
+      return null;
+    }
+    current = current[step];
+  }
+  return current === undefined ? null : current;
+}
+
+// Return a copy of value with the nested entry at path replaced. Only the containers along
+// the path are copied; every other branch is shared with the original. Missing objects
+// along the way are created
+export function setIn(value: any, path: ValuePath, newValue: any): any {
+  if (path.length === 0) {
+    return newValue;
+  }
+
+  const [step, ...rest] = path;
+  const child = isContainer(value) ? value[step] : undefined;
+  const updated = setIn(child, rest, newValue);
+
+  if (Array.isArray(value)) {
+    if (typeof step !== 'number' || !Number.isInteger(step) || step < 0 || step > value.length) {
+      throw new Error(`Cannot set index ${JSON.stringify(step)} of a list with ${value.length} items`);
+    }
+    const copy = value.slice();
+    copy[step] = updated;
+    return copy;
+  }
+
+  if (isContainer(value)) {
+    return { ...value, [step]: updated };
+  }
+
+  if (value !== null && value !== undefined) {
+    throw new Error(`Cannot set ${JSON.stringify(step)} on ${typeof value}`);
+  }
+  return { [step]: updated };
+}